@@ -113,4 +113,66 @@ var routes = Routes{
 		"health",
 		Handler{H: api.Health},
 	},
+
+	// swagger:operation GET /jobs/{id}/history Health History
+	// ---
+	// summary: History API
+	// description: Returns check results of the job
+	// parameters:
+	// - name: id
+	//   in: path
+	//   required: true
+	//   type: string
+	// - name: since
+	//   in: query
+	//   description: unix time of the oldest result
+	//   type: integer
+	// - name: limit
+	//   in: query
+	//   description: maximum number of latest results
+	//   type: integer
+	// responses:
+	//   "200":
+	//     description: "check results"
+	//     schema:
+	//       type: array
+	//       items:
+	//         "$ref": "#/definitions/CheckResult"
+	//   "400":
+	//     description: "Invalid query parameters"
+	//   "404":
+	//     description: "Job not found"
+	//   "500":
+	//     description: "Internal server error"
+	Route{
+		"History",
+		"GET",
+		"jobs/{id}/history",
+		Handler{H: api.History},
+	},
+
+	// swagger:operation GET /jobs/{id}/slo Health Slo
+	// ---
+	// summary: SLO API
+	// description: Returns availability, remaining error budget and burn rate of the job
+	// parameters:
+	// - name: id
+	//   in: path
+	//   required: true
+	//   type: string
+	// responses:
+	//   "200":
+	//     description: "slo report"
+	//     schema:
+	//       "$ref": "#/definitions/SloReport"
+	//   "404":
+	//     description: "Job not found"
+	//   "500":
+	//     description: "Internal server error"
+	Route{
+		"Slo",
+		"GET",
+		"jobs/{id}/slo",
+		Handler{H: api.Slo},
+	},
 }
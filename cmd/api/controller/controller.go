@@ -2,9 +2,12 @@ package controller
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
 
+	"github.com/gorilla/mux"
 	"github.com/healthcheck-watchdog/cmd/common"
 	"github.com/healthcheck-watchdog/cmd/healthcheck"
 	log "github.com/sirupsen/logrus"
@@ -66,3 +69,64 @@ func (api *ApiController) Ready(w http.ResponseWriter, _ *http.Request) error {
 
 	return err
 }
+
+// Job check results history
+func (api *ApiController) History(w http.ResponseWriter, r *http.Request) error {
+	var since int64
+	var limit int
+	var err error
+
+	query := r.URL.Query()
+	if v := query.Get("since"); v != "" {
+		since, err = strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("invalid since parameter: %s", v), http.StatusBadRequest)
+			return nil
+		}
+	}
+	if v := query.Get("limit"); v != "" {
+		limit, err = strconv.Atoi(v)
+		if err != nil || limit < 0 {
+			http.Error(w, fmt.Sprintf("invalid limit parameter: %s", v), http.StatusBadRequest)
+			return nil
+		}
+	}
+
+	results, err := api.Hc.History(mux.Vars(r)["id"], since, limit)
+	if errors.Is(err, healthcheck.ErrJobNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return nil
+	}
+	if err != nil {
+		log.Error(fmt.Sprintf("The HTTP request failed with error: %s", err.Error()))
+		return err
+	}
+
+	return writeJson(w, results)
+}
+
+// Job availability and error budget
+func (api *ApiController) Slo(w http.ResponseWriter, r *http.Request) error {
+	report, err := api.Hc.Slo(mux.Vars(r)["id"])
+	if errors.Is(err, healthcheck.ErrJobNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return nil
+	}
+	if err != nil {
+		log.Error(fmt.Sprintf("The HTTP request failed with error: %s", err.Error()))
+		return err
+	}
+
+	return writeJson(w, report)
+}
+
+func writeJson(w http.ResponseWriter, v interface{}) error {
+	w.Header().Set(common.HeaderContentType, common.ContentTypeJson)
+	err := json.NewEncoder(w).Encode(v)
+	if err != nil {
+		log.Error(fmt.Sprintf("The HTTP request failed with error: %s", err.Error()))
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+
+	return err
+}
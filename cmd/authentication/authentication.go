@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"sync"
 	"time"
 
 	"github.com/healthcheck-watchdog/cmd/model"
@@ -14,6 +15,7 @@ import (
 )
 
 type AuthClient struct {
+	mx     sync.Mutex
 	client *http.Client
 	oauth  *clientcredentials.Config
 	token  *oauth2.Token
@@ -50,19 +52,25 @@ func (ac *AuthClient) GetClient() *http.Client {
 	return ac.client
 }
 
-func (ac *AuthClient) GetToken() *oauth2.Token {
+// GetToken returns cached access token, fetching a new one when it expires.
+// Concurrent callers wait for a single fetch
+func (ac *AuthClient) GetToken() (*oauth2.Token, error) {
+	ac.mx.Lock()
+	defer ac.mx.Unlock()
+
 	ctx := context.Background()
-	var err error
 
 	if ac.token == nil || time.Now().After(ac.token.Expiry) {
-		ac.token, err = ac.oauth.Token(ctx)
+		token, err := ac.oauth.Token(ctx)
 		if err != nil {
 			log.Error(fmt.Sprintf("Error while get client token: %s", err.Error()))
+			return nil, err
 		}
+		ac.token = token
 
 		log.Info(fmt.Sprintf("Successfully obtained access token with lifetime until %s",
 			ac.token.Expiry.String()))
 	}
 
-	return ac.token
+	return ac.token, nil
 }
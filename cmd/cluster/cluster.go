@@ -1,14 +1,28 @@
 package cluster
 
 import (
+	"bufio"
 	"context"
 	"fmt"
 	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
 
+	"github.com/healthcheck-watchdog/cmd/common"
 	"github.com/healthcheck-watchdog/cmd/model"
+	"github.com/healthcheck-watchdog/cmd/store"
 	log "github.com/sirupsen/logrus"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
 	appsv1client "k8s.io/client-go/kubernetes/typed/apps/v1"
+	batchv1client "k8s.io/client-go/kubernetes/typed/batch/v1"
 	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
 	metrics "k8s.io/metrics/pkg/client/clientset/versioned"
 
@@ -18,15 +32,16 @@ import (
 )
 
 type Cluster struct {
-	client        *corev1client.CoreV1Client
-	appsClient    *appsv1client.AppsV1Client
-	metricsClient *metrics.Clientset
-	replicas      map[string]int32
+	client        corev1client.CoreV1Interface
+	appsClient    appsv1client.AppsV1Interface
+	batchClient   batchv1client.BatchV1Interface
+	metricsClient metrics.Interface
+	store         store.Store
 }
 
-func NewCluster(appConfig *model.Config) *Cluster {
+func NewCluster(appConfig *model.Config, st store.Store) *Cluster {
 	if appConfig.WatchDog.Namespace == "" && 
-		len(appConfig.WatchDog.Actions) == 0 {
+		len(appConfig.WatchDog.Actions) == 0 && !hasClusterJobs(appConfig.Jobs) {
 			log.Info("Missing watchdog configuration. Cluster configuration ignored.")
 			return nil
 		}
@@ -63,6 +78,11 @@ func NewCluster(appConfig *model.Config) *Cluster {
 		panic(err)
 	}
 
+	batchClient, err := batchv1client.NewForConfig(config)
+	if err != nil {
+		panic(err)
+	}
+
 	metricsClient, err := metrics.NewForConfig(config)
 	if err != nil {
 		panic(err)
@@ -71,13 +91,42 @@ func NewCluster(appConfig *model.Config) *Cluster {
 	wd := Cluster{
 		client:        coreClient,
 		appsClient:    appsClient,
+		batchClient:   batchClient,
 		metricsClient: metricsClient,
-		replicas:      make(map[string]int32),
+		store:         st,
 	}
 
 	return &wd
 }
 
+// NewClusterForClient creates cluster of kubernetes clientset without metrics API
+func NewClusterForClient(clientset kubernetes.Interface, st store.Store) *Cluster {
+	return NewClusterForClients(clientset, nil, st)
+}
+
+// NewClusterForClients creates cluster of kubernetes and metrics API clientsets
+func NewClusterForClients(clientset kubernetes.Interface, metricsClient metrics.Interface, st store.Store) *Cluster {
+	return &Cluster{
+		client:        clientset.CoreV1(),
+		appsClient:    clientset.AppsV1(),
+		batchClient:   clientset.BatchV1(),
+		metricsClient: metricsClient,
+		store:         st,
+	}
+}
+
+// hasClusterJobs reports whether any job is checked through kube API
+func hasClusterJobs(jobs []model.Job) bool {
+	for i := range jobs {
+		switch jobs[i].Type {
+		case "memory", "resources", "kubernetes", "cronjob", "logs":
+			return true
+		}
+	}
+
+	return false
+}
+
 // Scale down each deployment in namespace
 func (wd *Cluster) ScaleDown(names []string, namespace string) (err error) {
 	for i := range names {
@@ -103,7 +152,10 @@ func (wd *Cluster) scaleDown(name string, namespace string) error {
 
 	// remember scale count
 	if specs.Spec.Replicas > 0 {
-		wd.replicas[name] = specs.Spec.Replicas
+		err = wd.store.Put(common.StoreBucketReplicas, replicasKey(name, namespace), specs.Spec.Replicas)
+		if err != nil {
+			log.Error(fmt.Sprintf("error while save replicas of deployment %s: %s", name, err.Error()))
+		}
 	}
 	specs.Spec.Replicas = 0
 
@@ -143,10 +195,15 @@ func (wd *Cluster) scaleUp(name string, namespace string) error {
 	}
 
 	// restore scale count
-	if wd.replicas[name] == 0 {
-		wd.replicas[name] = 1
+	var replicas int32
+	_, err = wd.store.Get(common.StoreBucketReplicas, replicasKey(name, namespace), &replicas)
+	if err != nil {
+		log.Error(fmt.Sprintf("error while load replicas of deployment %s: %s", name, err.Error()))
 	}
-	specs.Spec.Replicas = wd.replicas[name]
+	if replicas == 0 {
+		replicas = 1
+	}
+	specs.Spec.Replicas = replicas
 
 	// scale up deployment
 	_, err = wd.appsClient.Deployments(namespace).
@@ -159,6 +216,10 @@ func (wd *Cluster) scaleUp(name string, namespace string) error {
 	return nil
 }
 
+func replicasKey(name string, namespace string) string {
+	return fmt.Sprintf("%s/%s", namespace, name)
+}
+
 func (wd *Cluster) DeletePod(name string, namespace string) error {
 	log.Info(fmt.Sprintf("killing %s in %s", name, namespace))
 
@@ -206,8 +267,8 @@ func (wd *Cluster) GetPodMemory(name string, namespace string) ([]int64, error)
 	}
 	podMetrics, err := wd.metricsClient.MetricsV1beta1().PodMetricses(namespace).List(context.Background(), options)
 	if err != nil {
-		fmt.Println("Error:", err)
-		return nil, nil
+		log.Error(fmt.Sprintf("error while get metrics of pods %s: %s", name, err.Error()))
+		return nil, err
 	}
 
 	result := make([]int64, 0)
@@ -357,3 +418,334 @@ func (wd *Cluster) Test() error {
 
 	return nil
 }
+
+// ContainerResources is usage of a container from metrics API with requests
+// and limits from pod spec. Cpu is in millicores, memory in bytes
+type ContainerResources struct {
+	Pod           string
+	Container     string
+	CpuUsage      int64
+	MemoryUsage   int64
+	CpuRequest    int64
+	CpuLimit      int64
+	MemoryRequest int64
+	MemoryLimit   int64
+}
+
+// GetPodResources returns usage, requests and limits of containers of pods matching selector
+func (wd *Cluster) GetPodResources(selector string, namespace string) ([]ContainerResources, error) {
+	options := metav1.ListOptions{
+		LabelSelector: selector,
+	}
+	podMetrics, err := wd.metricsClient.MetricsV1beta1().PodMetricses(namespace).List(context.Background(), options)
+	if err != nil {
+		log.Error(fmt.Sprintf("error while get metrics of pods %s: %s", selector, err.Error()))
+		return nil, err
+	}
+	pods, err := wd.client.Pods(namespace).List(context.Background(), options)
+	if err != nil {
+		log.Error(fmt.Sprintf("error while list pods %s: %s", selector, err.Error()))
+		return nil, err
+	}
+
+	specs := make(map[string]corev1.ResourceRequirements)
+	for _, pod := range pods.Items {
+		for _, container := range pod.Spec.Containers {
+			specs[pod.Name+"/"+container.Name] = container.Resources
+		}
+	}
+
+	result := make([]ContainerResources, 0)
+	for _, podMetric := range podMetrics.Items {
+		for _, container := range podMetric.Containers {
+			usage := ContainerResources{
+				Pod:         podMetric.Name,
+				Container:   container.Name,
+				CpuUsage:    container.Usage.Cpu().MilliValue(),
+				MemoryUsage: container.Usage.Memory().Value(),
+			}
+			if spec, found := specs[podMetric.Name+"/"+container.Name]; found {
+				usage.CpuRequest = spec.Requests.Cpu().MilliValue()
+				usage.CpuLimit = spec.Limits.Cpu().MilliValue()
+				usage.MemoryRequest = spec.Requests.Memory().Value()
+				usage.MemoryLimit = spec.Limits.Memory().Value()
+			}
+			result = append(result, usage)
+		}
+	}
+
+	return result, nil
+}
+
+// PodState is phase and container states of a pod
+type PodState struct {
+	Name       string
+	Phase      string
+	Created    time.Time
+	Containers []ContainerState
+}
+
+// ContainerState is restart count and waiting reason of a container
+type ContainerState struct {
+	Name     string
+	Restarts int32
+	Waiting  string
+}
+
+// GetWorkloadReplicas returns desired and available replicas of deployment,
+// statefulset or daemonset. Found is false for missing workload
+func (wd *Cluster) GetWorkloadReplicas(kind string, name string, namespace string) (desired int32, available int32, found bool, err error) {
+	switch strings.ToLower(kind) {
+	case common.KindDeployment:
+		deployment, err := wd.appsClient.Deployments(namespace).Get(context.Background(), name, metav1.GetOptions{})
+		if err != nil {
+			return 0, 0, !errors.IsNotFound(err), ignoreNotFound(err)
+		}
+		desired = 1
+		if deployment.Spec.Replicas != nil {
+			desired = *deployment.Spec.Replicas
+		}
+		return desired, deployment.Status.AvailableReplicas, true, nil
+	case common.KindStatefulSet:
+		statefulSet, err := wd.appsClient.StatefulSets(namespace).Get(context.Background(), name, metav1.GetOptions{})
+		if err != nil {
+			return 0, 0, !errors.IsNotFound(err), ignoreNotFound(err)
+		}
+		desired = 1
+		if statefulSet.Spec.Replicas != nil {
+			desired = *statefulSet.Spec.Replicas
+		}
+		return desired, statefulSet.Status.AvailableReplicas, true, nil
+	case common.KindDaemonSet:
+		daemonSet, err := wd.appsClient.DaemonSets(namespace).Get(context.Background(), name, metav1.GetOptions{})
+		if err != nil {
+			return 0, 0, !errors.IsNotFound(err), ignoreNotFound(err)
+		}
+		return daemonSet.Status.DesiredNumberScheduled, daemonSet.Status.NumberAvailable, true, nil
+	}
+
+	return 0, 0, false, fmt.Errorf("unsupported workload kind %s", kind)
+}
+
+// GetPodStates returns phase and container states of pods matching selector
+func (wd *Cluster) GetPodStates(selector string, namespace string) ([]PodState, error) {
+	pods, err := wd.client.Pods(namespace).List(context.Background(), metav1.ListOptions{
+		LabelSelector: selector,
+	})
+	if err != nil {
+		log.Error(fmt.Sprintf("error while list pods %s: %s", selector, err.Error()))
+		return nil, err
+	}
+
+	result := make([]PodState, 0, len(pods.Items))
+	for _, pod := range pods.Items {
+		state := PodState{
+			Name:    pod.Name,
+			Phase:   string(pod.Status.Phase),
+			Created: pod.CreationTimestamp.Time,
+		}
+		statuses := append(pod.Status.InitContainerStatuses, pod.Status.ContainerStatuses...)
+		for _, status := range statuses {
+			container := ContainerState{
+				Name:     status.Name,
+				Restarts: status.RestartCount,
+			}
+			if status.State.Waiting != nil {
+				container.Waiting = status.State.Waiting.Reason
+			}
+			state.Containers = append(state.Containers, container)
+		}
+		result = append(result, state)
+	}
+
+	return result, nil
+}
+
+// GetReadyEndpoints returns number of ready addresses of service endpoints.
+// Found is false for missing service
+func (wd *Cluster) GetReadyEndpoints(name string, namespace string) (int, bool, error) {
+	endpoints, err := wd.client.Endpoints(namespace).Get(context.Background(), name, metav1.GetOptions{})
+	if err != nil {
+		return 0, !errors.IsNotFound(err), ignoreNotFound(err)
+	}
+
+	ready := 0
+	for _, subset := range endpoints.Subsets {
+		ready += len(subset.Addresses)
+	}
+
+	return ready, true, nil
+}
+
+// CronJobState is schedule status of a cronjob with its jobs, newest first
+type CronJobState struct {
+	Created      time.Time
+	LastSchedule time.Time
+	LastSuccess  time.Time
+	Suspended    bool
+	Jobs         []JobState
+}
+
+// JobState is a job of cronjob. Reason is message of failed condition
+type JobState struct {
+	Name      string
+	Started   time.Time
+	Active    bool
+	Succeeded bool
+	Failed    bool
+	Reason    string
+}
+
+// GetCronJobState returns status of cronjob and jobs owned by it. Found is false for missing cronjob
+func (wd *Cluster) GetCronJobState(name string, namespace string) (*CronJobState, bool, error) {
+	cronJob, err := wd.batchClient.CronJobs(namespace).Get(context.Background(), name, metav1.GetOptions{})
+	if err != nil {
+		return nil, !errors.IsNotFound(err), ignoreNotFound(err)
+	}
+
+	state := CronJobState{
+		Created:   cronJob.CreationTimestamp.Time,
+		Suspended: cronJob.Spec.Suspend != nil && *cronJob.Spec.Suspend,
+	}
+	if cronJob.Status.LastScheduleTime != nil {
+		state.LastSchedule = cronJob.Status.LastScheduleTime.Time
+	}
+	if cronJob.Status.LastSuccessfulTime != nil {
+		state.LastSuccess = cronJob.Status.LastSuccessfulTime.Time
+	}
+
+	// jobs get labels of the job template. Without template labels all jobs of
+	// the namespace are listed and filtered by owner
+	jobs, err := wd.batchClient.Jobs(namespace).List(context.Background(), metav1.ListOptions{
+		LabelSelector: labels.SelectorFromSet(cronJob.Spec.JobTemplate.Labels).String(),
+	})
+	if err != nil {
+		log.Error(fmt.Sprintf("error while list jobs of cronjob %s: %s", name, err.Error()))
+		return nil, true, err
+	}
+	for _, job := range jobs.Items {
+		if !ownedBy(job.OwnerReferences, "CronJob", cronJob.UID) {
+			continue
+		}
+
+		jobState := JobState{
+			Name:    job.Name,
+			Started: job.CreationTimestamp.Time,
+		}
+		if job.Status.StartTime != nil {
+			jobState.Started = job.Status.StartTime.Time
+		}
+		for _, condition := range job.Status.Conditions {
+			if condition.Status != corev1.ConditionTrue {
+				continue
+			}
+			switch condition.Type {
+			case batchv1.JobComplete:
+				jobState.Succeeded = true
+			case batchv1.JobFailed:
+				jobState.Failed = true
+				jobState.Reason = condition.Reason
+				if condition.Message != "" {
+					jobState.Reason = fmt.Sprintf("%s: %s", condition.Reason, condition.Message)
+				}
+			}
+		}
+		jobState.Active = !jobState.Succeeded && !jobState.Failed
+		state.Jobs = append(state.Jobs, jobState)
+	}
+	sort.Slice(state.Jobs, func(i, j int) bool {
+		return state.Jobs[i].Started.After(state.Jobs[j].Started)
+	})
+
+	return &state, true, nil
+}
+
+// logLineLimit is maximal length of log line, longer lines fail the stream
+const logLineLimit = 1 << 20
+
+// StreamPodLogs reads logs of containers of running pods matching selector
+// written during since and calls handle for each line. Empty container reads all containers.
+// Containers are read in parallel until ctx is done, lines read before are handled
+func (wd *Cluster) StreamPodLogs(ctx context.Context, selector string, namespace string, container string, since time.Duration, handle func(pod string, container string, line string)) error {
+	pods, err := wd.client.Pods(namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: selector,
+	})
+	if err != nil {
+		log.Error(fmt.Sprintf("error while list pods %s: %s", selector, err.Error()))
+		return err
+	}
+
+	var mx sync.Mutex
+	var wg sync.WaitGroup
+	var streamErr error
+	seconds := int64(since.Seconds())
+	for _, pod := range pods.Items {
+		if pod.Status.Phase != corev1.PodRunning {
+			continue
+		}
+
+		for _, c := range pod.Spec.Containers {
+			if container != "" && c.Name != container {
+				continue
+			}
+
+			wg.Add(1)
+			go func(pod string, container string) {
+				defer wg.Done()
+				err := wd.streamContainerLogs(ctx, namespace, pod, container, seconds, func(line string) {
+					mx.Lock()
+					defer mx.Unlock()
+					handle(pod, container, line)
+				})
+				// stream cut by deadline keeps lines read so far
+				if err != nil && ctx.Err() == nil {
+					mx.Lock()
+					if streamErr == nil {
+						streamErr = fmt.Errorf("logs of pod %s container %s: %w", pod, container, err)
+					}
+					mx.Unlock()
+				}
+			}(pod.Name, c.Name)
+		}
+	}
+	wg.Wait()
+
+	return streamErr
+}
+
+func (wd *Cluster) streamContainerLogs(ctx context.Context, namespace string, pod string, container string, seconds int64, handle func(line string)) error {
+	stream, err := wd.client.Pods(namespace).GetLogs(pod, &corev1.PodLogOptions{
+		Container:    container,
+		SinceSeconds: &seconds,
+	}).Stream(ctx)
+	if err != nil {
+		return err
+	}
+	defer stream.Close()
+
+	scanner := bufio.NewScanner(stream)
+	scanner.Buffer(make([]byte, 0, 64*1024), logLineLimit)
+	for scanner.Scan() {
+		handle(scanner.Text())
+	}
+
+	return scanner.Err()
+}
+
+func ownedBy(references []metav1.OwnerReference, kind string, uid types.UID) bool {
+	for _, reference := range references {
+		if reference.Kind == kind && reference.UID == uid {
+			return true
+		}
+	}
+
+	return false
+}
+
+func ignoreNotFound(err error) error {
+	if errors.IsNotFound(err) {
+		return nil
+	}
+
+	return err
+}
@@ -0,0 +1,249 @@
+package cluster
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/healthcheck-watchdog/cmd/store"
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func replicas(n int32) *int32 {
+	return &n
+}
+
+func TestGetWorkloadReplicas(t *testing.T) {
+	cl := NewClusterForClient(fake.NewSimpleClientset(
+		&appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: "ns"},
+			Spec:       appsv1.DeploymentSpec{Replicas: replicas(3)},
+			Status:     appsv1.DeploymentStatus{AvailableReplicas: 1},
+		},
+		&appsv1.StatefulSet{
+			ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "ns"},
+			Spec:       appsv1.StatefulSetSpec{Replicas: replicas(2)},
+			Status:     appsv1.StatefulSetStatus{ReadyReplicas: 2, AvailableReplicas: 1},
+		},
+		&appsv1.DaemonSet{
+			ObjectMeta: metav1.ObjectMeta{Name: "agent", Namespace: "ns"},
+			Status:     appsv1.DaemonSetStatus{DesiredNumberScheduled: 3, NumberAvailable: 3},
+		},
+	), nil)
+
+	tests := []struct {
+		kind      string
+		name      string
+		desired   int32
+		available int32
+		found     bool
+	}{
+		{kind: "Deployment", name: "api", desired: 3, available: 1, found: true},
+		{kind: "statefulset", name: "db", desired: 2, available: 1, found: true},
+		{kind: "daemonset", name: "agent", desired: 3, available: 3, found: true},
+		{kind: "deployment", name: "missing", found: false},
+	}
+	for _, tt := range tests {
+		desired, available, found, err := cl.GetWorkloadReplicas(tt.kind, tt.name, "ns")
+		if err != nil {
+			t.Fatalf("%s %s: %v", tt.kind, tt.name, err)
+		}
+		if desired != tt.desired || available != tt.available || found != tt.found {
+			t.Errorf("%s %s: got %d/%d found %v, want %d/%d found %v",
+				tt.kind, tt.name, available, desired, found, tt.available, tt.desired, tt.found)
+		}
+	}
+
+	if _, _, _, err := cl.GetWorkloadReplicas("job", "x", "ns"); err == nil {
+		t.Error("expected error for unsupported kind")
+	}
+}
+
+func TestGetPodStates(t *testing.T) {
+	cl := NewClusterForClient(fake.NewSimpleClientset(
+		&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "api-1", Namespace: "ns", Labels: map[string]string{"app": "api"}},
+			Status: corev1.PodStatus{
+				Phase: corev1.PodRunning,
+				ContainerStatuses: []corev1.ContainerStatus{{
+					Name:         "app",
+					RestartCount: 4,
+					State:        corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "CrashLoopBackOff"}},
+				}},
+			},
+		},
+		&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: "ns", Labels: map[string]string{"app": "other"}},
+		},
+	), nil)
+
+	pods, err := cl.GetPodStates("app=api", "ns")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pods) != 1 || pods[0].Name != "api-1" || pods[0].Phase != "Running" {
+		t.Fatalf("unexpected pods %+v", pods)
+	}
+	if c := pods[0].Containers; len(c) != 1 || c[0].Restarts != 4 || c[0].Waiting != "CrashLoopBackOff" {
+		t.Errorf("unexpected containers %+v", c)
+	}
+}
+
+func TestGetReadyEndpoints(t *testing.T) {
+	cl := NewClusterForClient(fake.NewSimpleClientset(
+		&corev1.Endpoints{
+			ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: "ns"},
+			Subsets: []corev1.EndpointSubset{{
+				Addresses:         []corev1.EndpointAddress{{IP: "10.0.0.1"}, {IP: "10.0.0.2"}},
+				NotReadyAddresses: []corev1.EndpointAddress{{IP: "10.0.0.3"}},
+			}},
+		},
+		&corev1.Endpoints{
+			ObjectMeta: metav1.ObjectMeta{Name: "empty", Namespace: "ns"},
+			Subsets: []corev1.EndpointSubset{{
+				NotReadyAddresses: []corev1.EndpointAddress{{IP: "10.0.0.4"}},
+			}},
+		},
+	), nil)
+
+	tests := []struct {
+		name  string
+		ready int
+		found bool
+	}{
+		{name: "api", ready: 2, found: true},
+		{name: "empty", ready: 0, found: true},
+		{name: "missing", ready: 0, found: false},
+	}
+	for _, tt := range tests {
+		ready, found, err := cl.GetReadyEndpoints(tt.name, "ns")
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if ready != tt.ready || found != tt.found {
+			t.Errorf("%s: got %d found %v, want %d found %v", tt.name, ready, found, tt.ready, tt.found)
+		}
+	}
+}
+
+func TestGetCronJobState(t *testing.T) {
+	suspend := true
+	template := map[string]string{"app": "report"}
+	cronJob := &batchv1.CronJob{
+		ObjectMeta: metav1.ObjectMeta{Name: "report", Namespace: "ns", UID: "cj-1"},
+		Spec: batchv1.CronJobSpec{
+			Suspend:     &suspend,
+			JobTemplate: batchv1.JobTemplateSpec{ObjectMeta: metav1.ObjectMeta{Labels: template}},
+		},
+	}
+	owner := []metav1.OwnerReference{{Kind: "CronJob", UID: "cj-1"}}
+	now := time.Now()
+	job := func(name string, labels map[string]string, owners []metav1.OwnerReference, started time.Time, condition batchv1.JobConditionType) *batchv1.Job {
+		return &batchv1.Job{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "ns", Labels: labels, OwnerReferences: owners},
+			Status: batchv1.JobStatus{
+				StartTime:  &metav1.Time{Time: started},
+				Conditions: []batchv1.JobCondition{{Type: condition, Status: corev1.ConditionTrue, Reason: "BackoffLimitExceeded"}},
+			},
+		}
+	}
+
+	cl := NewClusterForClient(fake.NewSimpleClientset(
+		cronJob,
+		job("report-1", template, owner, now.Add(-2*time.Hour), batchv1.JobComplete),
+		job("report-2", template, owner, now.Add(-time.Hour), batchv1.JobFailed),
+		// owned job without template labels is not listed
+		job("report-0", nil, owner, now.Add(-3*time.Hour), batchv1.JobFailed),
+		job("other", template, nil, now, batchv1.JobFailed),
+	), nil)
+
+	state, found, err := cl.GetCronJobState("report", "ns")
+	if err != nil || !found {
+		t.Fatalf("expected cronjob, got found %v: %v", found, err)
+	}
+	if !state.Suspended {
+		t.Error("expected suspended cronjob")
+	}
+	if len(state.Jobs) != 2 || state.Jobs[0].Name != "report-2" || !state.Jobs[0].Failed || !state.Jobs[1].Succeeded {
+		t.Errorf("unexpected jobs %+v", state.Jobs)
+	}
+
+	if _, found, err := cl.GetCronJobState("missing", "ns"); found || err != nil {
+		t.Errorf("expected missing cronjob, got found %v: %v", found, err)
+	}
+}
+
+func TestStreamPodLogs(t *testing.T) {
+	pod := func(name string, phase corev1.PodPhase, containers ...string) *corev1.Pod {
+		p := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "ns", Labels: map[string]string{"app": "api"}},
+			Status:     corev1.PodStatus{Phase: phase},
+		}
+		for _, c := range containers {
+			p.Spec.Containers = append(p.Spec.Containers, corev1.Container{Name: c})
+		}
+		return p
+	}
+	cl := NewClusterForClient(fake.NewSimpleClientset(
+		pod("api-1", corev1.PodRunning, "app", "proxy"),
+		pod("api-2", corev1.PodRunning, "app"),
+		pod("api-3", corev1.PodPending, "app"),
+	), nil)
+
+	read := make(map[string]int)
+	err := cl.StreamPodLogs(context.Background(), "app=api", "ns", "", time.Minute, func(pod string, container string, line string) {
+		read[pod+"/"+container]++
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(read) != 3 || read["api-1/proxy"] != 1 || read["api-3/app"] != 0 {
+		t.Errorf("unexpected containers read %v", read)
+	}
+}
+
+func TestScaleUpRestoresReplicasAfterReopen(t *testing.T) {
+	// fake clientset doesn't implement scale subresource, keep scale of the deployment
+	scale := int32(3)
+	scaleClient := func() *fake.Clientset {
+		client := fake.NewSimpleClientset()
+		client.PrependReactor("get", "deployments", func(action k8stesting.Action) (bool, runtime.Object, error) {
+			return true, &autoscalingv1.Scale{ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: "ns"},
+				Spec: autoscalingv1.ScaleSpec{Replicas: scale}}, nil
+		})
+		client.PrependReactor("update", "deployments", func(action k8stesting.Action) (bool, runtime.Object, error) {
+			updated := action.(k8stesting.UpdateAction).GetObject().(*autoscalingv1.Scale)
+			scale = updated.Spec.Replicas
+			return true, updated, nil
+		})
+		return client
+	}
+
+	path := filepath.Join(t.TempDir(), "state.db")
+	st, err := store.NewBoltStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := NewClusterForClient(scaleClient(), st).ScaleDown([]string{"api"}, "ns"); err != nil || scale != 0 {
+		t.Fatalf("expected scaled down, got %d %v", scale, err)
+	}
+	st.Close()
+
+	// restart with the reopened store
+	st, err = store.NewBoltStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.Close()
+	if err := NewClusterForClient(scaleClient(), st).ScaleUp([]string{"api"}, "ns"); err != nil || scale != 3 {
+		t.Errorf("expected 3 replicas restored, got %d %v", scale, err)
+	}
+}
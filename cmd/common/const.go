@@ -1,5 +1,7 @@
 package common
 
+import "crypto/tls"
+
 // api
 const (
 	HeaderContentType = "Content-Type"
@@ -8,5 +10,220 @@ const (
 
 // redis
 const (
-	RedisFlushAll = "FLUSHALL"
+	RedisFlushAll    = "FLUSHALL"
+	RedisQueueList   = "list"
+	RedisQueueStream = "stream"
+
+	RedisModeStandalone = "standalone"
+	RedisModeSentinel   = "sentinel"
+	RedisModeCluster    = "cluster"
+)
+
+// check result status
+const (
+	StatusUp       = "up"
+	StatusDegraded = "degraded"
+	StatusDown     = "down"
+	StatusUnknown  = "unknown"
+)
+
+// check
+const (
+	DefaultCheckTimeout = 10
+)
+
+// history
+const (
+	DefaultHistorySize    = 1000
+	DefaultHistorySummary = 10
+)
+
+// store
+const (
+	StoreTypeBolt    = "bolt"
+	StoreTypeMemory  = "memory"
+	DefaultStorePath = "/data/state.db"
+
+	StoreBucketTasks    = "tasks"
+	StoreBucketActions  = "actions"
+	StoreBucketReplicas = "replicas"
+	StoreBucketSlo      = "slo"
+)
+
+// slo
+const (
+	DefaultSloWindow = "30d"
+	SloBucketSize    = 300
+)
+
+// SloWindows are rolling windows availability is reported for
+var SloWindows = []string{"1h", "24h", "7d", "30d"}
+
+// tcp
+const (
+	TcpTlsDirect      = "tls"
+	TcpTlsStartTls    = "starttls"
+	DefaultTcpTimeout = 10
+	TcpReadLimit      = 4096
+)
+
+// expected STARTTLS reply codes: exact greeting code and 2xx class of command replies
+const (
+	StartTlsGreetingCode = 220
+	StartTlsReplyCode    = 2
+)
+
+// DefaultStartTls is SMTP command sequence to upgrade connection with STARTTLS
+var DefaultStartTls = []string{"EHLO healthcheck-watchdog\r\n", "STARTTLS\r\n"}
+
+// dns
+const (
+	DefaultDnsResolver   = "127.0.0.1:53"
+	DefaultDnsRecordType = "A"
+	DefaultDnsRcode      = "NOERROR"
+	DnsResolvConf        = "/etc/resolv.conf"
+)
+
+// tls
+const (
+	DefaultTlsPort = "443"
+)
+
+// TlsVersions maps configured minimal tls version to protocol constant
+var TlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// grpc
+const (
+	GrpcWatchBackoff = 5
+)
+
+// exec
+const (
+	DefaultExecOutputLimit = 4096
+	// seconds to wait for output pipes after plugin is killed, e.g. held by its children
+	ExecWaitDelay = 2
+)
+
+// assertions
+const (
+	AssertionStatus   = "status"
+	AssertionHeader   = "header"
+	AssertionBody     = "body"
+	AssertionJsonPath = "jsonpath"
+	AssertionXPath    = "xpath"
+	AssertionBodySize = "bodySize"
+
+	DefaultAssertionOperator = "=="
+	DefaultBodyLimit         = 10 << 20
+)
+
+// http requests
+const (
+	DefaultMaxRedirects = 10
+	HttpVersion1        = "1.1"
+	HttpVersion2        = "2"
+	// bytes of unread response body discarded to reuse connection
+	DrainLimit = 64 << 10
 )
+
+// TemplateEnvPrefix limits environment variables available in ${NAME} placeholders
+const TemplateEnvPrefix = "WATCHDOG_"
+
+// scenario steps and extraction
+const (
+	StepHttp      = "http"
+	StepWebsocket = "websocket"
+
+	ExtractHeader   = "header"
+	ExtractJsonPath = "jsonpath"
+	ExtractRegex    = "regex"
+)
+
+// websocket authentication
+const (
+	WsAuthMessage = "message"
+	WsAuthHeader  = "header"
+	WsAuthQuery   = "query"
+
+	DefaultWsAuthMessage = `{"accessToken":"${token}"}`
+	DefaultWsAuthHeader  = "Authorization"
+	DefaultWsAuthQuery   = "access_token"
+	DefaultWsLabelPath   = "$[0].uid"
+)
+
+// stream connection states, reconnect backoff and websocket keepalive, seconds
+const (
+	StreamConnecting = "connecting"
+	StreamConnected  = "connected"
+	StreamBackoff    = "backoff"
+
+	WsPingInterval   = 10
+	WsPongWait       = 30
+	StreamBackoffMin = 1
+	StreamBackoffMax = 60
+)
+
+// stream check modes, request is websocket only
+const (
+	ModeStream     = "stream"
+	ModeRequest    = "request"
+	ModeThroughput = "throughput"
+
+	DefaultStreamInterval = 60
+)
+
+// websocket application protocols, raw frames by default
+const (
+	WsProtocolRaw      = "raw"
+	WsProtocolStomp    = "stomp"
+	WsProtocolSocketIo = "socketio"
+
+	// heartbeats are sent with pings, advertised interval in ms leaves a margin
+	// for late pings, the broker expects heartbeats at the advertised interval
+	StompHeartBeatSend            = 2 * WsPingInterval * 1000
+	StompHeartBeatReceive         = 10000
+	DefaultSocketIoNamespace      = "/"
+	DefaultSocketIoSubscribeEvent = "subscribe"
+	DefaultSocketIoRequestEvent   = "message"
+	DefaultSocketIoAuth           = `{"token":"${token}"}`
+)
+
+// resources check, percentage is computed of container limits by default
+const (
+	ResourceCpu    = "cpu"
+	ResourceMemory = "memory"
+
+	ResourceLimits   = "limits"
+	ResourceRequests = "requests"
+)
+
+// kubernetes workload kinds, pod states and cronjob history
+const (
+	KindDeployment  = "deployment"
+	KindStatefulSet = "statefulset"
+	KindDaemonSet   = "daemonset"
+
+	DefaultCronJobHistory = 1
+	DefaultRestartWindow  = 600
+	DefaultPendingTimeout = 300
+)
+
+// pod logs check
+const (
+	DefaultLogWindow  = 300
+	DefaultLogSamples = 5
+)
+
+// promql result types
+const (
+	PromqlVector = "vector"
+	PromqlScalar = "scalar"
+)
+
+// waiting reasons of unhealthy containers
+var UnhealthyWaitingReasons = []string{"CrashLoopBackOff", "ImagePullBackOff", "ErrImagePull", "CreateContainerConfigError", "InvalidImageName"}
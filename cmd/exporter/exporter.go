@@ -4,10 +4,10 @@ import (
 	"errors"
 	"fmt"
 
+	"github.com/healthcheck-watchdog/cmd/common"
 	"github.com/healthcheck-watchdog/cmd/model"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
-	dto "github.com/prometheus/client_model/go"
 	log "github.com/sirupsen/logrus"
 )
 
@@ -23,6 +23,27 @@ type Counter struct {
 	messagesCount  prometheus.GaugeVec
 	responseTime   prometheus.Gauge
 	watchdogAction prometheus.Gauge
+	availability   prometheus.GaugeVec
+	errorBudget    prometheus.Gauge
+	burnRate       prometheus.GaugeVec
+	unknown        prometheus.Gauge
+	// families of job types are nil for jobs of other types
+	certExpiry     *prometheus.GaugeVec
+	certInfo       *prometheus.GaugeVec
+	perfdata       *prometheus.GaugeVec
+	stepLatency    *prometheus.GaugeVec
+	stepStatus     *prometheus.GaugeVec
+	connState      *prometheus.GaugeVec
+	reconnects     *prometheus.CounterVec
+	messageAge     *prometheus.GaugeVec
+	messageGap     *prometheus.HistogramVec
+	messageRate    *prometheus.GaugeVec
+	podUsage       *prometheus.GaugeVec
+	containerUsage *prometheus.GaugeVec
+	usagePercent   *prometheus.GaugeVec
+	successAge     prometheus.Gauge
+	logMatches     *prometheus.GaugeVec
+	promqlValue    *prometheus.GaugeVec
 }
 
 func NewExporter(config *model.Config) *Exporter {
@@ -45,7 +66,7 @@ func NewExporter(config *model.Config) *Exporter {
 		})
 		status := promauto.NewGauge(prometheus.GaugeOpts{
 			Name: fmt.Sprintf("%s_status", config.Jobs[i].Id),
-			Help: fmt.Sprintf("%s работает (0: нет, 1: да)", config.Jobs[i].Description),
+			Help: fmt.Sprintf("%s работает (0: нет, 0.5: с задержкой, 1: да)", config.Jobs[i].Description),
 		})
 		messagesCount := promauto.NewGaugeVec(prometheus.GaugeOpts{
 			Name: fmt.Sprintf("%s_messages_count", config.Jobs[i].Id),
@@ -59,14 +80,41 @@ func NewExporter(config *model.Config) *Exporter {
 			Name: fmt.Sprintf("%s_watchdog_action_count", config.Jobs[i].Id),
 			Help: fmt.Sprintf("%s количество срабатываний watchdog", config.Jobs[i].Description),
 		})
-		counters[config.Jobs[i].Id] = &Counter{
+		availability := promauto.NewGaugeVec(prometheus.GaugeOpts{
+			Name: fmt.Sprintf("%s_availability", config.Jobs[i].Id),
+			Help: fmt.Sprintf("%s доступность, %%", config.Jobs[i].Description),
+		}, []string{"window"})
+		// error budget is defined only with slo target
+		var errorBudget prometheus.Gauge
+		if config.Jobs[i].Slo.Target > 0 {
+			errorBudget = promauto.NewGauge(prometheus.GaugeOpts{
+				Name: fmt.Sprintf("%s_error_budget_remaining", config.Jobs[i].Id),
+				Help: fmt.Sprintf("%s остаток бюджета ошибок (1: не израсходован)", config.Jobs[i].Description),
+			})
+		}
+		burnRate := promauto.NewGaugeVec(prometheus.GaugeOpts{
+			Name: fmt.Sprintf("%s_burn_rate", config.Jobs[i].Id),
+			Help: fmt.Sprintf("%s скорость расходования бюджета ошибок", config.Jobs[i].Description),
+		}, []string{"window"})
+		// any job is unknown when the watchdog can't evaluate it, e.g. on access token failure
+		unknown := promauto.NewGauge(prometheus.GaugeOpts{
+			Name: fmt.Sprintf("%s_unknown", config.Jobs[i].Id),
+			Help: fmt.Sprintf("%s не удалось проверить (0: нет, 1: да)", config.Jobs[i].Description),
+		})
+		counter := &Counter{
 			id:             config.Jobs[i].Id,
 			downtime:       downtime,
 			status:         status,
 			messagesCount:  *messagesCount,
 			responseTime:   responseTime,
 			watchdogAction: watchdogAction,
+			availability:   *availability,
+			errorBudget:    errorBudget,
+			burnRate:       *burnRate,
+			unknown:        unknown,
 		}
+		registerTypeCounters(counter, &config.Jobs[i])
+		counters[config.Jobs[i].Id] = counter
 
 		log.Info(fmt.Sprintf("Registered counter %s", config.Jobs[i].Id))
 	}
@@ -76,11 +124,93 @@ func NewExporter(config *model.Config) *Exporter {
 	return &ex
 }
 
-//todo config
+// registerTypeCounters registers families of the job type only, so jobs of
+// other types don't export constant zero values
+func registerTypeCounters(counter *Counter, job *model.Job) {
+	if job.Type == "tls" || job.Tls.Enabled {
+		counter.certExpiry = promauto.NewGaugeVec(prometheus.GaugeOpts{
+			Name: fmt.Sprintf("%s_certificate_expiry_days", job.Id),
+			Help: fmt.Sprintf("%s дней до истечения сертификата", job.Description),
+		}, []string{"host"})
+		counter.certInfo = promauto.NewGaugeVec(prometheus.GaugeOpts{
+			Name: fmt.Sprintf("%s_certificate_info", job.Id),
+			Help: fmt.Sprintf("%s сертификат", job.Description),
+		}, []string{"host", "subject", "issuer", "san"})
+	}
+
+	switch job.Type {
+	case "exec":
+		counter.perfdata = promauto.NewGaugeVec(prometheus.GaugeOpts{
+			Name: fmt.Sprintf("%s_perfdata", job.Id),
+			Help: fmt.Sprintf("%s данные производительности плагина", job.Description),
+		}, []string{"label", "uom", "field"})
+	case "scenario":
+		counter.stepLatency = promauto.NewGaugeVec(prometheus.GaugeOpts{
+			Name: fmt.Sprintf("%s_step_latency", job.Id),
+			Help: fmt.Sprintf("%s время выполнения шага сценария", job.Description),
+		}, []string{"step"})
+		counter.stepStatus = promauto.NewGaugeVec(prometheus.GaugeOpts{
+			Name: fmt.Sprintf("%s_step_status", job.Id),
+			Help: fmt.Sprintf("%s шаг сценария выполнен (0: нет, 1: да)", job.Description),
+		}, []string{"step"})
+	case "websocket", "sse":
+		counter.connState = promauto.NewGaugeVec(prometheus.GaugeOpts{
+			Name: fmt.Sprintf("%s_connection_state", job.Id),
+			Help: fmt.Sprintf("%s состояние соединения (connecting, connected, backoff)", job.Description),
+		}, []string{"url", "state"})
+		counter.reconnects = promauto.NewCounterVec(prometheus.CounterOpts{
+			Name: fmt.Sprintf("%s_reconnects_total", job.Id),
+			Help: fmt.Sprintf("%s количество переподключений", job.Description),
+		}, []string{"url"})
+		counter.messageAge = promauto.NewGaugeVec(prometheus.GaugeOpts{
+			Name: fmt.Sprintf("%s_last_message_age_seconds", job.Id),
+			Help: fmt.Sprintf("%s время с последнего сообщения, с", job.Description),
+		}, []string{"url"})
+		counter.messageGap = promauto.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    fmt.Sprintf("%s_message_gap_seconds", job.Id),
+			Help:    fmt.Sprintf("%s интервал между сообщениями, с", job.Description),
+			Buckets: prometheus.ExponentialBuckets(0.01, 2, 15),
+		}, []string{"url"})
+		counter.messageRate = promauto.NewGaugeVec(prometheus.GaugeOpts{
+			Name: fmt.Sprintf("%s_message_rate", job.Id),
+			Help: fmt.Sprintf("%s скорость поступления сообщений, в секунду", job.Description),
+		}, []string{"url"})
+	case "resources":
+		counter.podUsage = promauto.NewGaugeVec(prometheus.GaugeOpts{
+			Name: fmt.Sprintf("%s_pod_usage", job.Id),
+			Help: fmt.Sprintf("%s потребление ресурсов подом (cpu: ядра, memory: байты)", job.Description),
+		}, []string{"pod", "resource"})
+		counter.containerUsage = promauto.NewGaugeVec(prometheus.GaugeOpts{
+			Name: fmt.Sprintf("%s_container_usage", job.Id),
+			Help: fmt.Sprintf("%s потребление ресурсов контейнером (cpu: ядра, memory: байты)", job.Description),
+		}, []string{"pod", "container", "resource"})
+		counter.usagePercent = promauto.NewGaugeVec(prometheus.GaugeOpts{
+			Name: fmt.Sprintf("%s_container_usage_percent", job.Id),
+			Help: fmt.Sprintf("%s потребление ресурсов контейнером, %% от requests или limits", job.Description),
+		}, []string{"pod", "container", "resource", "of"})
+	case "cronjob":
+		counter.successAge = promauto.NewGauge(prometheus.GaugeOpts{
+			Name: fmt.Sprintf("%s_last_success_age_seconds", job.Id),
+			Help: fmt.Sprintf("%s время с последнего успешного запуска, с", job.Description),
+		})
+	case "logs":
+		counter.logMatches = promauto.NewGaugeVec(prometheus.GaugeOpts{
+			Name: fmt.Sprintf("%s_log_window_matches", job.Id),
+			Help: fmt.Sprintf("%s количество строк логов по шаблону за окно", job.Description),
+		}, []string{"pattern"})
+	case "promql":
+		counter.promqlValue = promauto.NewGaugeVec(prometheus.GaugeOpts{
+			Name: fmt.Sprintf("%s_promql_value", job.Id),
+			Help: fmt.Sprintf("%s значение запроса PromQL по сериям", job.Description),
+		}, []string{"series"})
+	}
+}
+
+// IncCounter counts received websocket message by its label
 func (ex *Exporter) IncCounter(id string, param string) {
 	counter, found := ex.counters[id]
 	if found {
-		counter.messagesCount.With(prometheus.Labels{"uid":param}).Inc()
+		counter.messagesCount.With(prometheus.Labels{"uid": param}).Inc()
 	}
 }
 
@@ -91,18 +221,35 @@ func (ex *Exporter) SetGauge(id string, value float64) {
 	}
 }
 
-func (ex *Exporter) SetCounter(id string, online bool) {
+// SetStatus exports job state: 1 up, 0.5 degraded, 0 down.
+// Unknown state is exported separately and keeps the last known status
+func (ex *Exporter) SetStatus(id string, state string) {
 	counter, found := ex.counters[id]
 	if found {
-		var onlineVal float64
-		if online {
-			onlineVal = 1
-		} else {
-			onlineVal = 0
+		if state == common.StatusUnknown {
+			counter.unknown.Set(1)
+			return
+		}
+		counter.unknown.Set(0)
+
+		var stateVal float64
+		switch state {
+		case common.StatusUp:
+			stateVal = 1
+		case common.StatusDegraded:
+			stateVal = 0.5
+		default:
+			stateVal = 0
 		}
-		counter.downtime.Set(0)
 
-		counter.status.Set(onlineVal)
+		counter.status.Set(stateVal)
+	}
+}
+
+func (ex *Exporter) ResetCounter(id string) {
+	counter, found := ex.counters[id]
+	if found {
+		counter.downtime.Set(0)
 	}
 }
 
@@ -110,18 +257,6 @@ func (ex *Exporter) AddCounter(id string, value int64) {
 	counter, found := ex.counters[id]
 	if found {
 		counter.downtime.Add(float64(value))
-
-		// todo what is this?
-		val2 := float64(0)
-		statusMetric := dto.Metric{
-			Counter: &dto.Counter{
-				Value: &val2,
-			},
-		}
-		err := counter.status.Write(&statusMetric)
-		if err != nil {
-			log.Error(fmt.Sprintf("Error writing metrics: %s", err.Error()))
-		}
 	}
 }
 
@@ -131,3 +266,166 @@ func (ex *Exporter) IncWatchdogActionCounter(id string) {
 		counter.watchdogAction.Inc()
 	}
 }
+
+func (ex *Exporter) SetSlo(report *model.SloReport) {
+	counter, found := ex.counters[report.Id]
+	if found {
+		for window, value := range report.Availability {
+			counter.availability.With(prometheus.Labels{"window": window}).Set(value)
+		}
+		if counter.errorBudget != nil && report.ErrorBudgetRemaining != nil {
+			counter.errorBudget.Set(*report.ErrorBudgetRemaining)
+		}
+		for window, value := range report.BurnRate {
+			counter.burnRate.With(prometheus.Labels{"window": window}).Set(value)
+		}
+	}
+}
+
+func (ex *Exporter) SetCertificate(id string, host string, days float64, subject string, issuer string, san string) {
+	counter, found := ex.counters[id]
+	if found && counter.certExpiry != nil {
+		counter.certExpiry.With(prometheus.Labels{"host": host}).Set(days)
+
+		// keep single info series per host when certificate is renewed
+		counter.certInfo.DeletePartialMatch(prometheus.Labels{"host": host})
+		counter.certInfo.With(prometheus.Labels{
+			"host":    host,
+			"subject": subject,
+			"issuer":  issuer,
+			"san":     san,
+		}).Set(1)
+	}
+}
+
+// SetPerfdata exports plugin perfdata field: value, warn, crit, min or max
+func (ex *Exporter) SetPerfdata(id string, label string, uom string, field string, value float64) {
+	counter, found := ex.counters[id]
+	if found && counter.perfdata != nil {
+		counter.perfdata.With(prometheus.Labels{"label": label, "uom": uom, "field": field}).Set(value)
+	}
+}
+
+// ResetPerfdata drops perfdata of the previous plugin run, so labels missing in the output are not exported
+func (ex *Exporter) ResetPerfdata(id string) {
+	counter, found := ex.counters[id]
+	if found && counter.perfdata != nil {
+		counter.perfdata.Reset()
+	}
+}
+
+// SetStep exports latency and result of scenario step. Latency of not reached step is kept
+func (ex *Exporter) SetStep(id string, step string, latency *float64, ok bool) {
+	counter, found := ex.counters[id]
+	if found && counter.stepLatency != nil {
+		if latency != nil {
+			counter.stepLatency.With(prometheus.Labels{"step": step}).Set(*latency)
+		}
+
+		var stepVal float64
+		if ok {
+			stepVal = 1
+		}
+		counter.stepStatus.With(prometheus.Labels{"step": step}).Set(stepVal)
+	}
+}
+
+// SetConnectionState exports connection state of the url: 1 for current state, 0 for others
+func (ex *Exporter) SetConnectionState(id string, url string, state string) {
+	counter, found := ex.counters[id]
+	if found && counter.connState != nil {
+		for _, s := range []string{common.StreamConnecting, common.StreamConnected, common.StreamBackoff} {
+			var stateVal float64
+			if s == state {
+				stateVal = 1
+			}
+			counter.connState.With(prometheus.Labels{"url": url, "state": s}).Set(stateVal)
+		}
+	}
+}
+
+func (ex *Exporter) IncReconnects(id string, url string) {
+	counter, found := ex.counters[id]
+	if found && counter.reconnects != nil {
+		counter.reconnects.With(prometheus.Labels{"url": url}).Inc()
+	}
+}
+
+func (ex *Exporter) SetMessageAge(id string, url string, seconds float64) {
+	counter, found := ex.counters[id]
+	if found && counter.messageAge != nil {
+		counter.messageAge.With(prometheus.Labels{"url": url}).Set(seconds)
+	}
+}
+
+func (ex *Exporter) ObserveMessageGap(id string, url string, seconds float64) {
+	counter, found := ex.counters[id]
+	if found && counter.messageGap != nil {
+		counter.messageGap.With(prometheus.Labels{"url": url}).Observe(seconds)
+	}
+}
+
+func (ex *Exporter) SetMessageRate(id string, url string, rate float64) {
+	counter, found := ex.counters[id]
+	if found && counter.messageRate != nil {
+		counter.messageRate.With(prometheus.Labels{"url": url}).Set(rate)
+	}
+}
+
+// ResetResources drops usage of pods from the previous check, so deleted pods are not exported
+func (ex *Exporter) ResetResources(id string) {
+	counter, found := ex.counters[id]
+	if found && counter.podUsage != nil {
+		counter.podUsage.Reset()
+		counter.containerUsage.Reset()
+		counter.usagePercent.Reset()
+	}
+}
+
+func (ex *Exporter) SetPodUsage(id string, pod string, resource string, value float64) {
+	counter, found := ex.counters[id]
+	if found && counter.podUsage != nil {
+		counter.podUsage.With(prometheus.Labels{"pod": pod, "resource": resource}).Set(value)
+	}
+}
+
+func (ex *Exporter) SetContainerUsage(id string, pod string, container string, resource string, value float64) {
+	counter, found := ex.counters[id]
+	if found && counter.containerUsage != nil {
+		counter.containerUsage.With(prometheus.Labels{"pod": pod, "container": container, "resource": resource}).Set(value)
+	}
+}
+
+func (ex *Exporter) SetContainerUsagePercent(id string, pod string, container string, resource string, of string, percent float64) {
+	counter, found := ex.counters[id]
+	if found && counter.usagePercent != nil {
+		counter.usagePercent.With(prometheus.Labels{"pod": pod, "container": container, "resource": resource, "of": of}).Set(percent)
+	}
+}
+
+func (ex *Exporter) SetLastSuccessAge(id string, seconds float64) {
+	counter, found := ex.counters[id]
+	if found && counter.successAge != nil {
+		counter.successAge.Set(seconds)
+	}
+}
+
+// SetLogWindowMatches exports count of log lines matching pattern within the
+// job window. It is a gauge recalculated on each check, not a counter
+func (ex *Exporter) SetLogWindowMatches(id string, pattern string, count int) {
+	counter, found := ex.counters[id]
+	if found && counter.logMatches != nil {
+		counter.logMatches.With(prometheus.Labels{"pattern": pattern}).Set(float64(count))
+	}
+}
+
+// SetPromqlValues exports values of series of the last query result, series missing in result are dropped
+func (ex *Exporter) SetPromqlValues(id string, values map[string]float64) {
+	counter, found := ex.counters[id]
+	if found && counter.promqlValue != nil {
+		counter.promqlValue.Reset()
+		for series, value := range values {
+			counter.promqlValue.With(prometheus.Labels{"series": series}).Set(value)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package exporter
+
+import (
+	"testing"
+
+	"github.com/healthcheck-watchdog/cmd/model"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestTypeCountersRegisteredForJobType(t *testing.T) {
+	ex := NewExporter(&model.Config{Jobs: []model.Job{
+		{Id: "families_http", Type: "http"},
+		{Id: "families_cronjob", Type: "cronjob"},
+		{Id: "families_https", Type: "http", Tls: model.Tls{Enabled: true}},
+	}})
+
+	// setters of families missing for the job type are ignored
+	ex.SetLastSuccessAge("families_http", 10)
+	ex.SetLastSuccessAge("families_cronjob", 10)
+	ex.SetCertificate("families_http", "api", 30, "CN=api", "CN=ca", "api")
+	ex.SetCertificate("families_https", "api", 30, "CN=api", "CN=ca", "api")
+	ex.SetPerfdata("families_http", "time", "s", "value", 1)
+	ex.ResetPerfdata("families_http")
+	ex.SetMessageAge("families_http", "ws://api", 1)
+	ex.SetPodUsage("families_cronjob", "api-1", "cpu", 0.5)
+	ex.SetPromqlValues("families_cronjob", map[string]float64{"{}": 1})
+
+	families, err := prometheus.DefaultGatherer.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := make(map[string]bool)
+	for _, family := range families {
+		names[family.GetName()] = true
+	}
+
+	for _, name := range []string{"families_cronjob_last_success_age_seconds", "families_https_certificate_expiry_days",
+		"families_http_status", "families_http_unknown", "families_cronjob_unknown"} {
+		if !names[name] {
+			t.Errorf("expected %s registered", name)
+		}
+	}
+	for _, name := range []string{"families_http_last_success_age_seconds", "families_http_certificate_expiry_days",
+		"families_http_perfdata", "families_cronjob_promql_value", "families_cronjob_pod_usage"} {
+		if names[name] {
+			t.Errorf("expected %s not registered", name)
+		}
+	}
+}
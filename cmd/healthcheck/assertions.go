@@ -0,0 +1,290 @@
+package healthcheck
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/PaesslerAG/jsonpath"
+	"github.com/antchfx/xmlquery"
+	"github.com/antchfx/xpath"
+	"github.com/healthcheck-watchdog/cmd/common"
+	"github.com/healthcheck-watchdog/cmd/model"
+)
+
+// checkResponse reads response body and evaluates assertions.
+// Without status assertion only 200 response code is accepted
+func checkResponse(assertions []model.Assertion, u string, resp *http.Response) ([]byte, error) {
+	body, err := io.ReadAll(io.LimitReader(resp.Body, bodyLimit(assertions)+1))
+	if err != nil {
+		return nil, fmt.Errorf("read body on url %s: %s", u, err.Error())
+	}
+
+	failures := assertResponse(assertions, resp.StatusCode, resp.Header, body)
+
+	statusChecked := false
+	for _, a := range assertions {
+		if a.Type == common.AssertionStatus {
+			statusChecked = true
+		}
+	}
+	if !statusChecked && resp.StatusCode != http.StatusOK {
+		failures = append([]string{fmt.Sprintf("invalid response code %d", resp.StatusCode)}, failures...)
+	}
+
+	if len(failures) > 0 {
+		return body, &AssertionError{Url: u, Failures: failures}
+	}
+
+	return body, nil
+}
+
+func bodyLimit(assertions []model.Assertion) int64 {
+	limit := int64(common.DefaultBodyLimit)
+	for _, a := range assertions {
+		if a.Type == common.AssertionBodySize && a.Max > 0 {
+			limit = a.Max
+		}
+	}
+
+	return limit
+}
+
+// assertResponse evaluates assertions on status code, headers and body
+// and returns failure messages
+func assertResponse(assertions []model.Assertion, code int, header http.Header, body []byte) []string {
+	failures := make([]string, 0)
+	for i := range assertions {
+		a := &assertions[i]
+
+		var err error
+		switch a.Type {
+		case common.AssertionStatus:
+			err = assertStatus(a, code)
+		case common.AssertionHeader:
+			err = assertHeader(a, header)
+		case common.AssertionBody:
+			err = compare("body", a.Operator, string(body), a.Value)
+		case common.AssertionJsonPath:
+			err = assertJsonPath(a, body)
+		case common.AssertionXPath:
+			err = assertXPath(a, body)
+		case common.AssertionBodySize:
+			if limit := bodyLimit(assertions); int64(len(body)) > limit {
+				err = fmt.Errorf("body size exceeded %d bytes", limit)
+			}
+		default:
+			err = fmt.Errorf("unsupported assertion type %s", a.Type)
+		}
+
+		if err != nil {
+			failures = append(failures, err.Error())
+		}
+	}
+
+	return failures
+}
+
+// assertStatus accepts exact codes (200), classes (2xx) and ranges (200-299)
+func assertStatus(a *model.Assertion, code int) error {
+	for _, expected := range a.Codes {
+		if from, to, found := strings.Cut(expected, "-"); found {
+			min, err1 := strconv.Atoi(strings.TrimSpace(from))
+			max, err2 := strconv.Atoi(strings.TrimSpace(to))
+			if err1 == nil && err2 == nil && code >= min && code <= max {
+				return nil
+			}
+			continue
+		}
+
+		if len(expected) == 3 && strings.HasSuffix(strings.ToLower(expected), "xx") {
+			if strconv.Itoa(code)[0] == expected[0] {
+				return nil
+			}
+			continue
+		}
+
+		if strconv.Itoa(code) == expected {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("invalid response code %d, expected %s", code, strings.Join(a.Codes, ","))
+}
+
+func assertHeader(a *model.Assertion, header http.Header) error {
+	values, found := header[http.CanonicalHeaderKey(a.Name)]
+	if !found {
+		return fmt.Errorf("missing header %s", a.Name)
+	}
+
+	return compare(fmt.Sprintf("header %s", a.Name), a.Operator, strings.Join(values, ","), a.Value)
+}
+
+func assertJsonPath(a *model.Assertion, body []byte) error {
+	var data interface{}
+	if err := json.Unmarshal(body, &data); err != nil {
+		return fmt.Errorf("invalid json body: %s", err.Error())
+	}
+
+	result, err := jsonpath.Get(a.Path, data)
+	if err != nil {
+		return fmt.Errorf("jsonpath %s: %s", a.Path, err.Error())
+	}
+
+	values := make([]string, 0)
+	if list, ok := result.([]interface{}); ok && isMultiPath(a.Path) {
+		for _, item := range list {
+			values = append(values, jsonString(item))
+		}
+	} else {
+		values = append(values, jsonString(result))
+	}
+
+	return compareAll(fmt.Sprintf("jsonpath %s", a.Path), a.Operator, values, a.Value)
+}
+
+func assertXPath(a *model.Assertion, body []byte) error {
+	expr, err := xpath.Compile(a.Path)
+	if err != nil {
+		return fmt.Errorf("invalid xpath %s: %s", a.Path, err.Error())
+	}
+
+	doc, err := xmlquery.Parse(bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("invalid xml body: %s", err.Error())
+	}
+
+	values := make([]string, 0)
+	switch result := expr.Evaluate(xmlquery.CreateXPathNavigator(doc)).(type) {
+	case *xpath.NodeIterator:
+		for result.MoveNext() {
+			values = append(values, result.Current().Value())
+		}
+	case float64:
+		values = append(values, strconv.FormatFloat(result, 'f', -1, 64))
+	case bool:
+		values = append(values, strconv.FormatBool(result))
+	case string:
+		values = append(values, result)
+	}
+
+	return compareAll(fmt.Sprintf("xpath %s", a.Path), a.Operator, values, a.Value)
+}
+
+// isMultiPath reports whether jsonpath selects a list of values:
+// wildcards, recursive descent, filters, unions and slices
+func isMultiPath(path string) bool {
+	for _, token := range []string{"*", "..", "?(", ",", ":"} {
+		if strings.Contains(path, token) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func jsonString(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case nil:
+		return "null"
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+
+	return string(data)
+}
+
+// compareAll requires at least one value and each value to satisfy the comparison
+func compareAll(subject string, operator string, actual []string, expected string) error {
+	if len(actual) == 0 {
+		return fmt.Errorf("%s matched nothing", subject)
+	}
+
+	for _, value := range actual {
+		if err := compare(subject, operator, value, expected); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// compare evaluates operator on actual and expected values.
+// Numeric operators require both values to be numbers
+func compare(subject string, operator string, actual string, expected string) error {
+	if operator == "" {
+		operator = common.DefaultAssertionOperator
+	}
+
+	var ok bool
+	switch operator {
+	case "exists":
+		ok = true
+	case "==":
+		ok = actual == expected || numbersEqual(actual, expected)
+	case "!=":
+		ok = actual != expected && !numbersEqual(actual, expected)
+	case "contains":
+		ok = strings.Contains(actual, expected)
+	case "matches":
+		re, err := regexp.Compile(expected)
+		if err != nil {
+			return fmt.Errorf("%s: invalid regex %s", subject, expected)
+		}
+		ok = re.MatchString(actual)
+	case ">", ">=", "<", "<=":
+		a, err1 := strconv.ParseFloat(actual, 64)
+		e, err2 := strconv.ParseFloat(expected, 64)
+		if err1 != nil || err2 != nil {
+			return fmt.Errorf("%s: %q and %q are not numbers", subject, actual, expected)
+		}
+		switch operator {
+		case ">":
+			ok = a > e
+		case ">=":
+			ok = a >= e
+		case "<":
+			ok = a < e
+		case "<=":
+			ok = a <= e
+		}
+	default:
+		return fmt.Errorf("%s: unsupported operator %s", subject, operator)
+	}
+
+	if !ok {
+		return fmt.Errorf("%s is %s, expected %s %s", subject, truncate(actual), operator, expected)
+	}
+
+	return nil
+}
+
+func numbersEqual(actual string, expected string) bool {
+	a, err1 := strconv.ParseFloat(actual, 64)
+	e, err2 := strconv.ParseFloat(expected, 64)
+
+	return err1 == nil && err2 == nil && a == e
+}
+
+// truncate shortens long values like response body in failure messages
+func truncate(value string) string {
+	const limit = 100
+	if len(value) > limit {
+		return value[:limit] + "..."
+	}
+
+	return value
+}
@@ -0,0 +1,171 @@
+package healthcheck
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/healthcheck-watchdog/cmd/model"
+)
+
+func TestCompare(t *testing.T) {
+	tests := []struct {
+		operator string
+		actual   string
+		expected string
+		failure  string
+	}{
+		{operator: "", actual: "ok", expected: "ok"},
+		{operator: "==", actual: "1.0", expected: "1"},
+		{operator: "==", actual: "ok", expected: "OK", failure: "value is ok, expected == OK"},
+		{operator: "!=", actual: "2", expected: "2.0", failure: "expected != 2.0"},
+		{operator: "!=", actual: "down", expected: "up"},
+		{operator: "exists", actual: ""},
+		{operator: "contains", actual: "status: ok", expected: "ok"},
+		{operator: "contains", actual: "status: down", expected: "ok", failure: "expected contains ok"},
+		{operator: "matches", actual: "v1.2.3", expected: `^v\d+\.\d+`},
+		{operator: "matches", actual: "v1", expected: "(", failure: "invalid regex ("},
+		{operator: ">", actual: "10", expected: "9.5"},
+		{operator: ">=", actual: "10", expected: "10"},
+		{operator: "<", actual: "10", expected: "10", failure: "expected < 10"},
+		{operator: "<=", actual: "-1", expected: "0"},
+		{operator: ">", actual: "ten", expected: "9", failure: `"ten" and "9" are not numbers`},
+		{operator: "~", actual: "a", expected: "a", failure: "unsupported operator ~"},
+	}
+	for _, tt := range tests {
+		err := compare("value", tt.operator, tt.actual, tt.expected)
+		if tt.failure == "" {
+			if err != nil {
+				t.Errorf("%s %q %q: unexpected error: %v", tt.operator, tt.actual, tt.expected, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.failure) {
+			t.Errorf("%s %q %q: expected %q, got %v", tt.operator, tt.actual, tt.expected, tt.failure, err)
+		}
+	}
+}
+
+func TestAssertStatus(t *testing.T) {
+	tests := []struct {
+		codes []string
+		code  int
+		ok    bool
+	}{
+		{codes: []string{"200"}, code: 200, ok: true},
+		{codes: []string{"200"}, code: 204, ok: false},
+		{codes: []string{"2xx"}, code: 204, ok: true},
+		{codes: []string{"2XX"}, code: 302, ok: false},
+		{codes: []string{"200-299"}, code: 299, ok: true},
+		{codes: []string{"200 - 299"}, code: 300, ok: false},
+		{codes: []string{"401", "3xx"}, code: 301, ok: true},
+		{codes: []string{"x-y"}, code: 200, ok: false},
+	}
+	for _, tt := range tests {
+		err := assertStatus(&model.Assertion{Type: "status", Codes: tt.codes}, tt.code)
+		if (err == nil) != tt.ok {
+			t.Errorf("%v %d: expected ok %t, got %v", tt.codes, tt.code, tt.ok, err)
+		}
+	}
+}
+
+func TestAssertResponse(t *testing.T) {
+	header := http.Header{"Content-Type": {"application/json"}, "Set-Cookie": {"a=1", "b=2"}}
+	jsonBody := `{"status":"ok","version":3,"items":[{"id":1,"ready":true},{"id":2,"ready":true}],"empty":[],"none":null}`
+	xmlBody := `<health><status>ok</status><node up="true">a</node><node up="true">b</node></health>`
+
+	tests := []struct {
+		name      string
+		assertion model.Assertion
+		body      string
+		failure   string
+	}{
+		{name: "header", assertion: model.Assertion{Type: "header", Name: "content-type", Operator: "contains", Value: "json"}},
+		{name: "header values", assertion: model.Assertion{Type: "header", Name: "Set-Cookie", Value: "a=1,b=2"}},
+		{name: "missing header", assertion: model.Assertion{Type: "header", Name: "X-Version", Operator: "exists"}, failure: "missing header X-Version"},
+		{name: "body", assertion: model.Assertion{Type: "body", Operator: "contains", Value: `"status":"ok"`}, body: jsonBody},
+		{name: "jsonpath", assertion: model.Assertion{Type: "jsonpath", Path: "$.status", Value: "ok"}, body: jsonBody},
+		{name: "jsonpath number", assertion: model.Assertion{Type: "jsonpath", Path: "$.version", Operator: ">=", Value: "2"}, body: jsonBody},
+		{name: "jsonpath null", assertion: model.Assertion{Type: "jsonpath", Path: "$.none", Value: "null"}, body: jsonBody},
+		{name: "jsonpath object", assertion: model.Assertion{Type: "jsonpath", Path: "$.items[0]", Value: `{"id":1,"ready":true}`}, body: jsonBody},
+		{name: "jsonpath wildcard", assertion: model.Assertion{Type: "jsonpath", Path: "$.items[*].ready", Value: "true"}, body: jsonBody},
+		{name: "jsonpath filter", assertion: model.Assertion{Type: "jsonpath", Path: "$.items[?(@.id == 2)].id", Value: "2"}, body: jsonBody},
+		{name: "jsonpath each value", assertion: model.Assertion{Type: "jsonpath", Path: "$.items[*].id", Value: "1"}, body: jsonBody,
+			failure: "jsonpath $.items[*].id is 2, expected == 1"},
+		{name: "jsonpath matched nothing", assertion: model.Assertion{Type: "jsonpath", Path: "$.empty[*]", Operator: "exists"}, body: jsonBody,
+			failure: "jsonpath $.empty[*] matched nothing"},
+		{name: "jsonpath missing key", assertion: model.Assertion{Type: "jsonpath", Path: "$.missing", Operator: "exists"}, body: jsonBody,
+			failure: "jsonpath $.missing: "},
+		{name: "invalid json", assertion: model.Assertion{Type: "jsonpath", Path: "$.status", Value: "ok"}, body: "<html/>",
+			failure: "invalid json body"},
+		{name: "xpath", assertion: model.Assertion{Type: "xpath", Path: "/health/status", Value: "ok"}, body: xmlBody},
+		{name: "xpath nodes", assertion: model.Assertion{Type: "xpath", Path: "//node/@up", Value: "true"}, body: xmlBody},
+		{name: "xpath count", assertion: model.Assertion{Type: "xpath", Path: "count(//node)", Value: "2"}, body: xmlBody},
+		{name: "xpath boolean", assertion: model.Assertion{Type: "xpath", Path: "boolean(//status)", Value: "true"}, body: xmlBody},
+		{name: "xpath matched nothing", assertion: model.Assertion{Type: "xpath", Path: "//missing", Operator: "exists"}, body: xmlBody,
+			failure: "xpath //missing matched nothing"},
+		{name: "invalid xpath", assertion: model.Assertion{Type: "xpath", Path: "//["}, body: xmlBody, failure: "invalid xpath //["},
+		{name: "body size", assertion: model.Assertion{Type: "bodySize", Max: 10}, body: "0123456789"},
+		{name: "body size exceeded", assertion: model.Assertion{Type: "bodySize", Max: 10}, body: "0123456789a", failure: "body size exceeded 10 bytes"},
+		{name: "type", assertion: model.Assertion{Type: "cookie"}, failure: "unsupported assertion type cookie"},
+	}
+	for _, tt := range tests {
+		failures := assertResponse([]model.Assertion{tt.assertion}, 200, header, []byte(tt.body))
+		if tt.failure == "" {
+			if len(failures) > 0 {
+				t.Errorf("%s: unexpected failures %q", tt.name, failures)
+			}
+			continue
+		}
+		if len(failures) != 1 || !strings.Contains(failures[0], tt.failure) {
+			t.Errorf("%s: expected %q, got %q", tt.name, tt.failure, failures)
+		}
+	}
+}
+
+func TestIsMultiPath(t *testing.T) {
+	tests := map[string]bool{
+		"$.status":             false,
+		"$.items[0].id":        false,
+		"$['status']":          false,
+		"$.items[*].id":        true,
+		"$..id":                true,
+		"$.items[?(@.id > 1)]": true,
+		"$.items[0,1]":         true,
+		"$.items[0:2]":         true,
+	}
+	for path, expected := range tests {
+		if isMultiPath(path) != expected {
+			t.Errorf("%s: expected %t", path, expected)
+		}
+	}
+}
+
+func TestCheckResponse(t *testing.T) {
+	response := func(code int, body string) *http.Response {
+		return &http.Response{StatusCode: code, Header: http.Header{}, Body: io.NopCloser(strings.NewReader(body))}
+	}
+
+	if _, err := checkResponse(nil, "http://api", response(200, "ok")); err != nil {
+		t.Errorf("expected 200 accepted by default, got %v", err)
+	}
+
+	_, err := checkResponse([]model.Assertion{{Type: "body", Value: "ok"}}, "http://api", response(503, "down"))
+	var assertionErr *AssertionError
+	if !errors.As(err, &assertionErr) || len(assertionErr.Failures) != 2 ||
+		assertionErr.Failures[0] != "invalid response code 503" || !strings.Contains(err.Error(), "on url http://api") {
+		t.Errorf("expected response code and body failures, got %v", err)
+	}
+
+	if _, err := checkResponse([]model.Assertion{{Type: "status", Codes: []string{"503"}}}, "http://api", response(503, "down")); err != nil {
+		t.Errorf("expected status assertion to replace default code, got %v", err)
+	}
+
+	// body is read up to bodySize max and one more byte
+	body, err := checkResponse([]model.Assertion{{Type: "bodySize", Max: 4}}, "http://api", response(200, "0123456789"))
+	if string(body) != "01234" || err == nil || !strings.Contains(err.Error(), "body size exceeded 4 bytes") {
+		t.Errorf("expected limited body and size failure, got %q %v", body, err)
+	}
+}
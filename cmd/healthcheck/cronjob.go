@@ -0,0 +1,88 @@
+package healthcheck
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/healthcheck-watchdog/cmd/common"
+	"github.com/healthcheck-watchdog/cmd/model"
+)
+
+// checkCronJob checks age of the last successful run of cronjob, failures of
+// its most recent finished jobs and jobs active longer than activeDeadline.
+// Suspended cronjob is degraded, its runs are not checked
+func (hc *HealthCheck) checkCronJob(function *model.Job) error {
+	if hc.cluster == nil {
+		return unknown(errors.New("cluster is not configured"))
+	}
+
+	config := &function.CronJob
+	if config.Name == "" {
+		return unknown(errors.New("missing cronjob name"))
+	}
+
+	state, found, err := hc.cluster.GetCronJobState(config.Name, function.Namespace)
+	if err != nil {
+		return unknown(fmt.Errorf("kube api: cronjob %s: %w", config.Name, err))
+	}
+	if !found {
+		return fmt.Errorf("cronjob %s not found", config.Name)
+	}
+	if state.Suspended {
+		return degraded(fmt.Errorf("cronjob %s is suspended", config.Name))
+	}
+
+	failures := make([]string, 0)
+
+	// cronjob which never succeeded is as old as the cronjob itself
+	lastSuccess := state.LastSuccess
+	if lastSuccess.IsZero() {
+		lastSuccess = state.Created
+	}
+	age := time.Since(lastSuccess)
+	hc.exporter.SetLastSuccessAge(function.Id, age.Seconds())
+	if maxAge := time.Duration(config.MaxAge) * time.Second; maxAge > 0 && age > maxAge {
+		if state.LastSuccess.IsZero() {
+			failures = append(failures, fmt.Sprintf("cronjob %s never succeeded since creation %s ago, max %s",
+				config.Name, age.Round(time.Second), maxAge))
+		} else {
+			failures = append(failures, fmt.Sprintf("cronjob %s last succeeded %s ago, max %s",
+				config.Name, age.Round(time.Second), maxAge))
+		}
+	}
+
+	history := config.History
+	if history <= 0 {
+		history = common.DefaultCronJobHistory
+	}
+	finished, failed := 0, make([]string, 0)
+	for _, job := range state.Jobs {
+		if job.Active {
+			if deadline := time.Duration(config.ActiveDeadline) * time.Second; deadline > 0 {
+				if active := time.Since(job.Started); active > deadline {
+					failures = append(failures, fmt.Sprintf("job %s active for %s, deadline %s", job.Name, active.Round(time.Second), deadline))
+				}
+			}
+			continue
+		}
+
+		if finished < history {
+			finished++
+			if job.Failed {
+				failed = append(failed, fmt.Sprintf("%s (%s)", job.Name, job.Reason))
+			}
+		}
+	}
+	if len(failed) > config.MaxFailed {
+		failures = append(failures, fmt.Sprintf("cronjob %s: %d of %d recent jobs failed: %s",
+			config.Name, len(failed), finished, strings.Join(failed, ", ")))
+	}
+
+	if len(failures) > 0 {
+		return errors.New(strings.Join(failures, "; "))
+	}
+
+	return nil
+}
@@ -0,0 +1,23 @@
+package healthcheck
+
+import (
+	"testing"
+
+	"github.com/healthcheck-watchdog/cmd/model"
+	batchv1 "k8s.io/api/batch/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestCheckCronJobSuspended(t *testing.T) {
+	suspend := true
+	hc := kubernetesHealthCheck(fake.NewSimpleClientset(&batchv1.CronJob{
+		ObjectMeta: metav1.ObjectMeta{Name: "report", Namespace: "ns"},
+		Spec:       batchv1.CronJobSpec{Suspend: &suspend},
+	}))
+
+	err := hc.checkCronJob(&model.Job{Id: "c", Namespace: "ns", CronJob: model.CronJob{Name: "report", MaxAge: 60}})
+	if !isDegraded(err) || err.Error() != "cronjob report is suspended" {
+		t.Errorf("expected degraded suspended cronjob, got %v", err)
+	}
+}
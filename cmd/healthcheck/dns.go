@@ -0,0 +1,128 @@
+package healthcheck
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/healthcheck-watchdog/cmd/common"
+	"github.com/healthcheck-watchdog/cmd/model"
+	"github.com/miekg/dns"
+	log "github.com/sirupsen/logrus"
+)
+
+// checkDns resolves configured name and asserts on rcode, record count and answers
+func (hc *HealthCheck) checkDns(function *model.Job) error {
+	if function.Dns.Name == "" {
+		return unknown(errors.New("missing dns name"))
+	}
+
+	recordType := strings.ToUpper(function.Dns.RecordType)
+	if recordType == "" {
+		recordType = common.DefaultDnsRecordType
+	}
+	qtype, found := dns.StringToType[recordType]
+	if !found {
+		return unknown(fmt.Errorf("unsupported dns record type %s", function.Dns.RecordType))
+	}
+
+	expectedRcode := strings.ToUpper(function.Dns.Rcode)
+	if expectedRcode == "" {
+		expectedRcode = common.DefaultDnsRcode
+	}
+	if _, found := dns.StringToRcode[expectedRcode]; !found {
+		return unknown(fmt.Errorf("unsupported dns rcode %s", function.Dns.Rcode))
+	}
+
+	timeout := checkTimeout(function)
+
+	client := &dns.Client{
+		Net:     function.Dns.Protocol,
+		Timeout: timeout,
+	}
+	msg := new(dns.Msg)
+	msg.SetQuestion(dns.Fqdn(function.Dns.Name), qtype)
+
+	resolver := dnsResolver(function)
+	response, rtt, err := client.Exchange(msg, resolver)
+	if err != nil {
+		return fmt.Errorf("dns query %s %s on %s: %s", recordType, function.Dns.Name, resolver, err.Error())
+	}
+
+	hc.exporter.SetGauge(function.Id, float64(rtt.Milliseconds()))
+	log.Info(fmt.Sprintf("%s %s", function.Id, rtt))
+
+	rcode := dns.RcodeToString[response.Rcode]
+	if rcode != expectedRcode {
+		return fmt.Errorf("dns query %s %s returned %s, expected %s", recordType, function.Dns.Name, rcode, expectedRcode)
+	}
+
+	answers := make([]string, 0, len(response.Answer))
+	for _, rr := range response.Answer {
+		if rr.Header().Rrtype == qtype {
+			answers = append(answers, dnsAnswer(rr))
+		}
+	}
+
+	if len(answers) < function.Dns.MinRecords {
+		return fmt.Errorf("dns query %s %s returned %d records, expected at least %d",
+			recordType, function.Dns.Name, len(answers), function.Dns.MinRecords)
+	}
+
+	for _, expected := range function.Dns.Expected {
+		if !containsAnswer(answers, expected) {
+			return fmt.Errorf("dns query %s %s answers %v don't contain %s",
+				recordType, function.Dns.Name, answers, expected)
+		}
+	}
+
+	return nil
+}
+
+// dnsResolver returns configured resolver or the first one from resolv.conf
+func dnsResolver(function *model.Job) string {
+	if function.Dns.Resolver != "" {
+		if _, _, err := net.SplitHostPort(function.Dns.Resolver); err != nil {
+			return net.JoinHostPort(function.Dns.Resolver, "53")
+		}
+		return function.Dns.Resolver
+	}
+
+	config, err := dns.ClientConfigFromFile(common.DnsResolvConf)
+	if err != nil || len(config.Servers) == 0 {
+		return common.DefaultDnsResolver
+	}
+
+	return net.JoinHostPort(config.Servers[0], config.Port)
+}
+
+// dnsAnswer formats record data the way it is expected in configuration
+func dnsAnswer(rr dns.RR) string {
+	switch r := rr.(type) {
+	case *dns.A:
+		return r.A.String()
+	case *dns.AAAA:
+		return r.AAAA.String()
+	case *dns.CNAME:
+		return strings.TrimSuffix(r.Target, ".")
+	case *dns.SRV:
+		return net.JoinHostPort(strings.TrimSuffix(r.Target, "."), strconv.Itoa(int(r.Port)))
+	case *dns.TXT:
+		return strings.Join(r.Txt, "")
+	}
+
+	return strings.TrimPrefix(rr.String(), rr.Header().String())
+}
+
+func containsAnswer(answers []string, expected string) bool {
+	expected = strings.TrimSuffix(expected, ".")
+	for _, answer := range answers {
+		if strings.EqualFold(answer, expected) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,91 @@
+package healthcheck
+
+import (
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/healthcheck-watchdog/cmd/exporter"
+	"github.com/healthcheck-watchdog/cmd/model"
+	"github.com/miekg/dns"
+)
+
+// dnsServer serves api.test. A records, www.test. CNAME and NXDOMAIN for other names
+func dnsServer(t *testing.T) string {
+	t.Helper()
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	handler := dns.HandlerFunc(func(w dns.ResponseWriter, r *dns.Msg) {
+		m := new(dns.Msg)
+		m.SetReply(r)
+		q := r.Question[0]
+		switch {
+		case q.Name == "api.test." && q.Qtype == dns.TypeA:
+			for _, ip := range []string{"10.0.0.1", "10.0.0.2"} {
+				rr, _ := dns.NewRR("api.test. 60 IN A " + ip)
+				m.Answer = append(m.Answer, rr)
+			}
+		case q.Name == "www.test.":
+			rr, _ := dns.NewRR("www.test. 60 IN CNAME api.test.")
+			m.Answer = append(m.Answer, rr)
+		case q.Name != "api.test.":
+			m.Rcode = dns.RcodeNameError
+		}
+		w.WriteMsg(m) //nolint:errcheck // test server
+	})
+
+	started := make(chan struct{})
+	server := &dns.Server{PacketConn: conn, Handler: handler, NotifyStartedFunc: func() { close(started) }}
+	go server.ActivateAndServe() //nolint:errcheck // stopped by cleanup
+	t.Cleanup(func() { server.Shutdown() })
+	<-started
+
+	return conn.LocalAddr().String()
+}
+
+func TestCheckDns(t *testing.T) {
+	hc := &HealthCheck{exporter: &exporter.Exporter{}}
+	resolver := dnsServer(t)
+
+	tests := []struct {
+		name    string
+		dns     model.Dns
+		failure string
+	}{
+		{name: "answers", dns: model.Dns{Name: "api.test", Expected: []string{"10.0.0.2"}, MinRecords: 2}},
+		{name: "cname", dns: model.Dns{Name: "www.test", RecordType: "cname", Expected: []string{"api.test."}}},
+		{name: "missing answer", dns: model.Dns{Name: "api.test", Expected: []string{"10.0.0.3"}}, failure: "don't contain 10.0.0.3"},
+		{name: "record count", dns: model.Dns{Name: "api.test", MinRecords: 3}, failure: "returned 2 records, expected at least 3"},
+		{name: "no records", dns: model.Dns{Name: "api.test", RecordType: "AAAA", MinRecords: 1}, failure: "returned 0 records"},
+		{name: "nxdomain", dns: model.Dns{Name: "gone.test"}, failure: "returned NXDOMAIN, expected NOERROR"},
+		{name: "expected nxdomain", dns: model.Dns{Name: "gone.test", Rcode: "nxdomain"}},
+		{name: "unsupported type", dns: model.Dns{Name: "api.test", RecordType: "BOGUS"}, failure: "unsupported dns record type"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.dns.Resolver = resolver
+			err := hc.checkDns(&model.Job{Id: "d", ResponseTimeout: 2, Dns: tt.dns})
+			if tt.failure == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.failure) {
+				t.Errorf("expected %q, got %v", tt.failure, err)
+			}
+		})
+	}
+}
+
+func TestDnsResolver(t *testing.T) {
+	if resolver := dnsResolver(&model.Job{Dns: model.Dns{Resolver: "10.0.0.53"}}); resolver != "10.0.0.53:53" {
+		t.Errorf("expected default port, got %s", resolver)
+	}
+	if resolver := dnsResolver(&model.Job{Dns: model.Dns{Resolver: "10.0.0.53:5353"}}); resolver != "10.0.0.53:5353" {
+		t.Errorf("expected configured port, got %s", resolver)
+	}
+}
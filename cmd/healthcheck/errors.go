@@ -0,0 +1,78 @@
+package healthcheck
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// UnknownError marks a check the watchdog itself could not evaluate:
+// metrics API, token endpoint or kube API failures. Such results say
+// nothing about the monitored service.
+type UnknownError struct {
+	Err error
+}
+
+func (e *UnknownError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *UnknownError) Unwrap() error {
+	return e.Err
+}
+
+func unknown(err error) error {
+	return &UnknownError{Err: err}
+}
+
+func isUnknown(err error) bool {
+	var e *UnknownError
+	return errors.As(err, &e)
+}
+
+// DegradedError marks a check of the service that works with issues,
+// e.g. warning of a nagios plugin
+type DegradedError struct {
+	Err error
+}
+
+func (e *DegradedError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *DegradedError) Unwrap() error {
+	return e.Err
+}
+
+func degraded(err error) error {
+	return &DegradedError{Err: err}
+}
+
+func isDegraded(err error) bool {
+	var e *DegradedError
+	return errors.As(err, &e)
+}
+
+// AssertionError lists failed assertions of the response
+type AssertionError struct {
+	Url      string
+	Failures []string
+}
+
+func (e *AssertionError) Error() string {
+	return fmt.Sprintf("%s on url %s", strings.Join(e.Failures, "; "), e.Url)
+}
+
+// StepError marks failed step of a scenario
+type StepError struct {
+	Step string
+	Err  error
+}
+
+func (e *StepError) Error() string {
+	return fmt.Sprintf("step %s: %s", e.Step, e.Err.Error())
+}
+
+func (e *StepError) Unwrap() error {
+	return e.Err
+}
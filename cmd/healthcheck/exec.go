@@ -0,0 +1,237 @@
+package healthcheck
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/healthcheck-watchdog/cmd/common"
+	"github.com/healthcheck-watchdog/cmd/model"
+)
+
+// nagios plugin exit codes
+const (
+	execOk       = 0
+	execWarning  = 1
+	execCritical = 2
+)
+
+var perfValuePattern = regexp.MustCompile(`^([-+]?[0-9]*\.?[0-9]+(?:[eE][-+]?[0-9]+)?)(.*)$`)
+
+var perfFields = []string{"value", "warn", "crit", "min", "max"}
+
+// checkExec runs nagios compatible plugin and maps its exit code to job status
+func (hc *HealthCheck) checkExec(function *model.Job) (string, error) {
+	if function.Exec.Command == "" {
+		return "", unknown(errors.New("missing exec command"))
+	}
+
+	timeout := checkTimeout(function)
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	limit := function.Exec.OutputLimit
+	if limit <= 0 {
+		limit = common.DefaultExecOutputLimit
+	}
+	// status line and perfdata are read from stdout only, stderr is added to the output message
+	stdout := &limitedBuffer{limit: limit}
+	stderr := &limitedBuffer{limit: limit}
+
+	cmd := exec.CommandContext(ctx, function.Exec.Command, function.Exec.Args...) //nolint:gosec // command is set by configuration
+	cmd.Dir = function.Exec.Dir
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	cmd.WaitDelay = common.ExecWaitDelay * time.Second
+	if len(function.Exec.Env) > 0 {
+		cmd.Env = os.Environ()
+		for key, value := range function.Exec.Env {
+			cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%s", key, value))
+		}
+	}
+
+	err := cmd.Run()
+	text := pluginOutput(stdout.String(), stderr.String())
+	// labels missing in this run are not exported, perfdata of truncated
+	// output can be cut in the middle of a value
+	hc.exporter.ResetPerfdata(function.Id)
+	if !stdout.truncated {
+		hc.exportPerfdata(function.Id, stdout.String())
+	}
+
+	// nagios reports plugin timeout as UNKNOWN
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return text, unknown(fmt.Errorf("plugin timed out after %s", timeout))
+	}
+
+	var exitErr *exec.ExitError
+	code := execOk
+	if errors.As(err, &exitErr) {
+		code = exitErr.ExitCode()
+	} else if err != nil {
+		// plugin can't be started
+		return text, unknown(fmt.Errorf("run plugin: %w", err))
+	}
+
+	status := pluginStatus(stdout.String(), stderr.String(), code)
+	switch code {
+	case execOk:
+		return text, nil
+	case execWarning:
+		return text, degraded(errors.New(status))
+	case execCritical:
+		return text, errors.New(status)
+	}
+
+	return text, unknown(errors.New(status))
+}
+
+// pluginStatus returns the first line of plugin stdout without perfdata,
+// the first line of stderr is added when stdout is empty
+func pluginStatus(stdout string, stderr string, code int) string {
+	line, _, _ := strings.Cut(stdout, "\n")
+	line, _, _ = strings.Cut(line, "|")
+	line = strings.TrimSpace(line)
+	if line != "" {
+		return line
+	}
+
+	line, _, _ = strings.Cut(strings.TrimSpace(stderr), "\n")
+	if line != "" {
+		return fmt.Sprintf("plugin exited with code %d: %s", code, strings.TrimSpace(line))
+	}
+
+	return fmt.Sprintf("plugin exited with code %d", code)
+}
+
+// pluginOutput joins stdout and stderr of the plugin for check result output
+func pluginOutput(stdout string, stderr string) string {
+	stderr = strings.TrimSpace(stderr)
+	if stderr == "" {
+		return stdout
+	}
+	if stdout == "" {
+		return stderr
+	}
+
+	return strings.TrimRight(stdout, "\n") + "\n" + stderr
+}
+
+// exportPerfdata parses perfdata of the first output line and the long output
+// after the first '|' and exports it as gauges
+func (hc *HealthCheck) exportPerfdata(id string, output string) {
+	lines := strings.Split(output, "\n")
+
+	perf := make([]string, 0)
+	if _, data, found := strings.Cut(lines[0], "|"); found {
+		perf = append(perf, data)
+	}
+
+	long := false
+	for _, line := range lines[1:] {
+		if long {
+			perf = append(perf, line)
+		} else if _, data, found := strings.Cut(line, "|"); found {
+			perf = append(perf, data)
+			long = true
+		}
+	}
+
+	for _, data := range perf {
+		for _, metric := range parsePerfdata(data) {
+			for i, value := range metric.values {
+				if value != nil {
+					hc.exporter.SetPerfdata(id, metric.label, metric.uom, perfFields[i], *value)
+				}
+			}
+		}
+	}
+}
+
+type perfMetric struct {
+	label  string
+	uom    string
+	values []*float64
+}
+
+// parsePerfdata parses space separated 'label'=value[UOM];[warn];[crit];[min];[max] items.
+// Range thresholds like 10:20 are skipped
+func parsePerfdata(data string) []perfMetric {
+	result := make([]perfMetric, 0)
+
+	data = strings.TrimSpace(data)
+	for data != "" {
+		var label string
+		if strings.HasPrefix(data, "'") {
+			end := strings.Index(data[1:], "'=")
+			if end < 0 {
+				break
+			}
+			label = strings.ReplaceAll(data[1:end+1], "''", "'")
+			data = data[end+3:]
+		} else {
+			eq := strings.Index(data, "=")
+			if eq < 0 {
+				break
+			}
+			label = data[:eq]
+			data = data[eq+1:]
+		}
+
+		item, rest, _ := strings.Cut(data, " ")
+		data = strings.TrimSpace(rest)
+
+		fields := strings.Split(item, ";")
+		match := perfValuePattern.FindStringSubmatch(fields[0])
+		if match == nil {
+			continue
+		}
+
+		metric := perfMetric{
+			label:  label,
+			uom:    match[2],
+			values: make([]*float64, len(perfFields)),
+		}
+		fields[0] = match[1]
+		for i := 0; i < len(fields) && i < len(perfFields); i++ {
+			if value, err := strconv.ParseFloat(fields[i], 64); err == nil {
+				metric.values[i] = &value
+			}
+		}
+		result = append(result, metric)
+	}
+
+	return result
+}
+
+// limitedBuffer keeps first limit bytes of the output and discards the rest
+type limitedBuffer struct {
+	buf       bytes.Buffer
+	limit     int
+	truncated bool
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	remaining := b.limit - b.buf.Len()
+	if len(p) > remaining {
+		b.truncated = true
+		if remaining > 0 {
+			b.buf.Write(p[:remaining])
+		}
+	} else {
+		b.buf.Write(p)
+	}
+
+	return len(p), nil
+}
+
+func (b *limitedBuffer) String() string {
+	return b.buf.String()
+}
@@ -0,0 +1,100 @@
+package healthcheck
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/healthcheck-watchdog/cmd/exporter"
+	"github.com/healthcheck-watchdog/cmd/model"
+)
+
+func TestParsePerfdata(t *testing.T) {
+	value := func(v float64) *float64 { return &v }
+
+	tests := []struct {
+		data     string
+		expected []perfMetric
+	}{
+		{data: "time=0.5s;1;2;0;10", expected: []perfMetric{
+			{label: "time", uom: "s", values: []*float64{value(0.5), value(1), value(2), value(0), value(10)}}}},
+		{data: "'disk usage'=80% 'it''s'=3", expected: []perfMetric{
+			{label: "disk usage", uom: "%", values: []*float64{value(80), nil, nil, nil, nil}},
+			{label: "it's", uom: "", values: []*float64{value(3), nil, nil, nil, nil}}}},
+		{data: "load=1.5;10:20;;0", expected: []perfMetric{
+			{label: "load", uom: "", values: []*float64{value(1.5), nil, nil, value(0), nil}}}},
+		{data: "size=1e3B;;", expected: []perfMetric{
+			{label: "size", uom: "B", values: []*float64{value(1000), nil, nil, nil, nil}}}},
+		{data: "state=U missing", expected: []perfMetric{}},
+		{data: "", expected: []perfMetric{}},
+	}
+	for _, tt := range tests {
+		if result := parsePerfdata(tt.data); !reflect.DeepEqual(result, tt.expected) {
+			t.Errorf("%q: expected %v, got %v", tt.data, tt.expected, result)
+		}
+	}
+}
+
+func TestCheckExecExitCode(t *testing.T) {
+	tests := []struct {
+		name    string
+		script  string
+		status  string
+		message string
+		output  string
+	}{
+		{name: "ok", script: "echo 'OK - fine|time=1s'", status: "up", output: "OK - fine|time=1s\n"},
+		{name: "warning", script: "echo 'WARNING - slow'; exit 1", status: "degraded", message: "WARNING - slow"},
+		{name: "critical", script: "echo 'CRITICAL - down' ; echo 'stderr noise' >&2; exit 2", status: "down",
+			message: "CRITICAL - down", output: "CRITICAL - down\nstderr noise"},
+		{name: "unknown", script: "echo 'UNKNOWN - no data'; exit 3", status: "unknown", message: "UNKNOWN - no data"},
+		{name: "stderr only", script: "echo 'no such host' >&2; exit 2", status: "down",
+			message: "plugin exited with code 2: no such host", output: "no such host"},
+		{name: "timeout", script: "exec sleep 5", status: "unknown", message: "plugin timed out"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hc := &HealthCheck{exporter: &exporter.Exporter{}}
+			output, err := hc.checkExec(&model.Job{Id: "e", ResponseTimeout: 1,
+				Exec: model.Exec{Command: "sh", Args: []string{"-c", tt.script}}})
+
+			status := "up"
+			switch {
+			case err == nil:
+			case isUnknown(err):
+				status = "unknown"
+			case isDegraded(err):
+				status = "degraded"
+			default:
+				status = "down"
+			}
+			if status != tt.status {
+				t.Errorf("expected %s, got %s (%v)", tt.status, status, err)
+			}
+			if tt.message != "" && (err == nil || !strings.Contains(err.Error(), tt.message)) {
+				t.Errorf("expected %q, got %v", tt.message, err)
+			}
+			if tt.output != "" && output != tt.output {
+				t.Errorf("expected output %q, got %q", tt.output, output)
+			}
+		})
+	}
+}
+
+func TestLimitedBuffer(t *testing.T) {
+	b := &limitedBuffer{limit: 8}
+	for _, chunk := range []string{"OK - ", "abc", "def"} {
+		if n, err := b.Write([]byte(chunk)); n != len(chunk) || err != nil {
+			t.Fatalf("expected %d bytes written, got %d %v", len(chunk), n, err)
+		}
+	}
+	if b.String() != "OK - abc" || !b.truncated {
+		t.Errorf("expected truncated %q, got %q truncated %t", "OK - abc", b.String(), b.truncated)
+	}
+
+	b = &limitedBuffer{limit: 8}
+	b.Write([]byte("OK - abc")) //nolint:errcheck // never fails
+	if b.truncated {
+		t.Error("expected output of limit size not truncated")
+	}
+}
@@ -0,0 +1,250 @@
+package healthcheck
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/healthcheck-watchdog/cmd/common"
+	"github.com/healthcheck-watchdog/cmd/model"
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+)
+
+// GrpcClient keeps connections and watch streams of grpc jobs
+type GrpcClient struct {
+	mx          sync.Mutex
+	ctx         context.Context
+	cancel      context.CancelFunc
+	connections map[string]*grpc.ClientConn
+	watches     map[string]*grpcWatch
+}
+
+// grpcWatch holds the latest status received from Watch stream
+type grpcWatch struct {
+	mx     sync.Mutex
+	status healthpb.HealthCheckResponse_ServingStatus
+	err    error
+	// notify is called when status changes
+	notify func()
+}
+
+func NewGrpcClient() *GrpcClient {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &GrpcClient{
+		ctx:         ctx,
+		cancel:      cancel,
+		connections: make(map[string]*grpc.ClientConn),
+		watches:     make(map[string]*grpcWatch),
+	}
+}
+
+// Close stops watch streams and closes connections
+func (gc *GrpcClient) Close() {
+	gc.mx.Lock()
+	defer gc.mx.Unlock()
+
+	gc.cancel()
+	for key, conn := range gc.connections {
+		if err := conn.Close(); err != nil {
+			log.Error(fmt.Sprintf("grpc close %s: %s", key, err.Error()))
+		}
+	}
+	gc.connections = make(map[string]*grpc.ClientConn)
+	gc.watches = make(map[string]*grpcWatch)
+}
+
+func (gc *GrpcClient) getConnection(function *model.Job, target string) (*grpc.ClientConn, error) {
+	gc.mx.Lock()
+	defer gc.mx.Unlock()
+
+	key := fmt.Sprintf("%s/%s", function.Id, target)
+	if conn, found := gc.connections[key]; found {
+		return conn, nil
+	}
+
+	creds := insecure.NewCredentials()
+	if function.Grpc.Tls {
+		creds = credentials.NewTLS(&tls.Config{
+			InsecureSkipVerify: function.Grpc.InsecureSkipVerify, //nolint:gosec // opt-in by configuration
+		})
+	}
+
+	conn, err := grpc.Dial(target, grpc.WithTransportCredentials(creds))
+	if err != nil {
+		return nil, err
+	}
+	gc.connections[key] = conn
+
+	return conn, nil
+}
+
+// getWatch returns watch of the target, starting Watch stream on first call.
+// Status changes trigger the next check of the job right away
+func (gc *GrpcClient) getWatch(hc *HealthCheck, function *model.Job, target string, conn *grpc.ClientConn) *grpcWatch {
+	gc.mx.Lock()
+	defer gc.mx.Unlock()
+
+	key := fmt.Sprintf("%s/%s", function.Id, target)
+	watch, found := gc.watches[key]
+	if !found {
+		watch = &grpcWatch{
+			err:    unknown(errors.New("waiting for first watch status")),
+			notify: func() { hc.trigger(function.Id) },
+		}
+		gc.watches[key] = watch
+		go watch.run(gc.ctx, hc, function, target, conn)
+	}
+
+	return watch
+}
+
+func (w *grpcWatch) set(status healthpb.HealthCheckResponse_ServingStatus, err error) {
+	w.mx.Lock()
+	changed := w.status != status || (w.err == nil) != (err == nil)
+	w.status = status
+	w.err = err
+	w.mx.Unlock()
+
+	if changed {
+		w.notify()
+	}
+}
+
+func (w *grpcWatch) get() (healthpb.HealthCheckResponse_ServingStatus, error) {
+	w.mx.Lock()
+	defer w.mx.Unlock()
+
+	return w.status, w.err
+}
+
+// run receives status changes and reopens stream after failures until the
+// client is closed. Stream is reopened with a new token when access token expires
+func (w *grpcWatch) run(parent context.Context, hc *HealthCheck, function *model.Job, target string, conn *grpc.ClientConn) {
+	for parent.Err() == nil {
+		expired, err := w.watch(parent, hc, function, target, conn)
+		if parent.Err() != nil {
+			return
+		}
+		if expired {
+			log.Info(fmt.Sprintf("%s: grpc watch on %s is reopened with new access token", function.Id, target))
+			continue
+		}
+
+		log.Error(fmt.Sprintf("%s: grpc watch on %s failed: %s", function.Id, target, err.Error()))
+		w.set(healthpb.HealthCheckResponse_UNKNOWN, err)
+		select {
+		case <-parent.Done():
+		case <-time.After(common.GrpcWatchBackoff * time.Second):
+		}
+	}
+}
+
+// watch receives statuses until the stream fails or access token expires
+func (w *grpcWatch) watch(parent context.Context, hc *HealthCheck, function *model.Job, target string, conn *grpc.ClientConn) (bool, error) {
+	ctx, expiry, err := hc.grpcContext(parent, function)
+	if err != nil {
+		return false, err
+	}
+	var cancel context.CancelFunc
+	if expiry.IsZero() {
+		ctx, cancel = context.WithCancel(ctx)
+	} else {
+		ctx, cancel = context.WithDeadline(ctx, expiry)
+	}
+	defer cancel()
+
+	stream, err := healthpb.NewHealthClient(conn).Watch(ctx,
+		&healthpb.HealthCheckRequest{Service: function.Grpc.Service})
+	for err == nil {
+		var resp *healthpb.HealthCheckResponse
+		resp, err = stream.Recv()
+		if err == nil {
+			log.Info(fmt.Sprintf("%s: grpc watch status of %s: %s", function.Id, target, resp.Status))
+			w.set(resp.Status, nil)
+		}
+	}
+
+	return errors.Is(ctx.Err(), context.DeadlineExceeded), err
+}
+
+// grpcContext adds configured metadata and bearer token to outgoing context.
+// Expiry of the token is zero without auth_enabled
+func (hc *HealthCheck) grpcContext(ctx context.Context, function *model.Job) (context.Context, time.Time, error) {
+	md := metadata.New(function.Grpc.Metadata)
+	var expiry time.Time
+	if function.AuthEnabled {
+		token, err := hc.authClient.GetToken()
+		if err != nil {
+			return nil, expiry, unknown(fmt.Errorf("access token: %w", err))
+		}
+		md.Set("authorization", "Bearer "+token.AccessToken)
+		expiry = token.Expiry
+	}
+
+	return metadata.NewOutgoingContext(ctx, md), expiry, nil
+}
+
+// checkGrpc calls grpc.health.v1.Health on each target
+func (hc *HealthCheck) checkGrpc(function *model.Job) error {
+	timeout := checkTimeout(function)
+
+	start := time.Now()
+	for _, target := range function.Urls {
+		conn, err := hc.grpcClient.getConnection(function, target)
+		if err != nil {
+			return fmt.Errorf("grpc connect to %s: %s", target, err.Error())
+		}
+
+		var status healthpb.HealthCheckResponse_ServingStatus
+		if function.Grpc.Watch {
+			status, err = hc.grpcClient.getWatch(hc, function, target, conn).get()
+		} else {
+			status, err = hc.grpcCheck(function, conn, timeout)
+		}
+		if isUnknown(err) {
+			return err
+		}
+		if err != nil {
+			return fmt.Errorf("grpc health of %s: %s", target, err.Error())
+		}
+
+		// UNKNOWN status is reported by the server, so it is down like NOT_SERVING
+		if status != healthpb.HealthCheckResponse_SERVING {
+			return fmt.Errorf("grpc health of %s is %s", target, status)
+		}
+	}
+
+	if !function.Grpc.Watch {
+		hc.exporter.SetGauge(function.Id, float64(time.Since(start).Milliseconds()))
+		log.Info(fmt.Sprintf("%s %s", function.Id, time.Since(start)))
+	}
+
+	return nil
+}
+
+func (hc *HealthCheck) grpcCheck(function *model.Job, conn *grpc.ClientConn, timeout time.Duration) (healthpb.HealthCheckResponse_ServingStatus, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	ctx, _, err := hc.grpcContext(ctx, function)
+	if err != nil {
+		return healthpb.HealthCheckResponse_UNKNOWN, err
+	}
+
+	resp, err := healthpb.NewHealthClient(conn).Check(ctx,
+		&healthpb.HealthCheckRequest{Service: function.Grpc.Service})
+	if err != nil {
+		return healthpb.HealthCheckResponse_UNKNOWN, err
+	}
+
+	return resp.Status, nil
+}
@@ -0,0 +1,80 @@
+package healthcheck
+
+import (
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/healthcheck-watchdog/cmd/model"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+func grpcHealthServer(t *testing.T) (*health.Server, string) {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := grpc.NewServer()
+	healthServer := health.NewServer()
+	healthpb.RegisterHealthServer(server, healthServer)
+	go server.Serve(listener) //nolint:errcheck // stopped by cleanup
+	t.Cleanup(server.Stop)
+
+	return healthServer, listener.Addr().String()
+}
+
+func TestCheckGrpcUnknownStatusIsDown(t *testing.T) {
+	healthServer, target := grpcHealthServer(t)
+	healthServer.SetServingStatus("api", healthpb.HealthCheckResponse_UNKNOWN)
+
+	hc := &HealthCheck{grpcClient: NewGrpcClient()}
+	defer hc.grpcClient.Close()
+
+	err := hc.checkGrpc(&model.Job{Id: "g", Urls: []string{target}, Grpc: model.Grpc{Service: "api"}})
+	if err == nil || isUnknown(err) || !strings.Contains(err.Error(), "is UNKNOWN") {
+		t.Errorf("expected down on UNKNOWN serving status, got %v", err)
+	}
+}
+
+func TestCheckGrpcWatchTriggersCheck(t *testing.T) {
+	healthServer, target := grpcHealthServer(t)
+	healthServer.SetServingStatus("api", healthpb.HealthCheckResponse_SERVING)
+
+	hc := &HealthCheck{
+		grpcClient: NewGrpcClient(),
+		triggers:   map[string]chan struct{}{"g": make(chan struct{}, 1)},
+	}
+	function := &model.Job{Id: "g", Urls: []string{target}, Grpc: model.Grpc{Service: "api", Watch: true}}
+
+	waitTrigger := func() {
+		t.Helper()
+		select {
+		case <-hc.triggers["g"]:
+		case <-time.After(5 * time.Second):
+			t.Fatal("status change didn't trigger check")
+		}
+	}
+
+	if err := hc.checkGrpc(function); !isUnknown(err) {
+		t.Fatalf("expected unknown before first watch status, got %v", err)
+	}
+	waitTrigger()
+	if err := hc.checkGrpc(function); err != nil {
+		t.Fatalf("expected serving, got %v", err)
+	}
+
+	healthServer.SetServingStatus("api", healthpb.HealthCheckResponse_NOT_SERVING)
+	waitTrigger()
+	if err := hc.checkGrpc(function); err == nil || !strings.Contains(err.Error(), "NOT_SERVING") {
+		t.Errorf("expected NOT_SERVING, got %v", err)
+	}
+
+	hc.grpcClient.Close()
+	if len(hc.grpcClient.watches) != 0 || hc.grpcClient.ctx.Err() == nil {
+		t.Error("expected watches stopped on close")
+	}
+}
@@ -2,16 +2,21 @@ package healthcheck
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"io"
-	"net/http"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/healthcheck-watchdog/cmd/authentication"
 	"github.com/healthcheck-watchdog/cmd/cluster"
+	"github.com/healthcheck-watchdog/cmd/common"
 	"github.com/healthcheck-watchdog/cmd/exporter"
+	"github.com/healthcheck-watchdog/cmd/history"
 	"github.com/healthcheck-watchdog/cmd/model"
+	"github.com/healthcheck-watchdog/cmd/redis"
+	"github.com/healthcheck-watchdog/cmd/slo"
+	"github.com/healthcheck-watchdog/cmd/store"
 	"github.com/healthcheck-watchdog/cmd/watchdog"
 	log "github.com/sirupsen/logrus"
 )
@@ -20,25 +25,60 @@ type HealthCheck struct {
 	config     *model.Config
 	authClient *authentication.AuthClient
 	status     *model.Status
-	wsClient   *GorillaWsClient
+	wsClient   *WsClient
+	sseClient  *SseClient
 	exporter   *exporter.Exporter
 	watchDog   *watchdog.WatchDog
-	httpClient *http.Client
+	httpClient *HttpClient
 	cluster    *cluster.Cluster
+	restarts   *restartTracker
+	grpcClient *GrpcClient
+	redis      *redis.Redis
+	sqlClient  *SqlClient
+	history    *history.History
+	store      store.Store
+	slo        *slo.Tracker
+	// triggers wake up task to run the next check before its timeout
+	triggers map[string]chan struct{}
+	// ctx stops the tasks, wg waits for running checks on close
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
 }
 
-func NewHealthCheck(config *model.Config, authClient *authentication.AuthClient, ex *exporter.Exporter, wd *watchdog.WatchDog, cl *cluster.Cluster) *HealthCheck {
+// ErrJobNotFound is returned for requests to jobs missing in configuration
+var ErrJobNotFound = errors.New("job not found")
+
+func NewHealthCheck(config *model.Config, authClient *authentication.AuthClient, ex *exporter.Exporter, wd *watchdog.WatchDog, cl *cluster.Cluster, hs *history.History, st store.Store, sl *slo.Tracker) *HealthCheck {
+	if err := validateConfig(config); err != nil {
+		log.Error(fmt.Sprintf("Invalid configuration: %s", err.Error()))
+		panic(err)
+	}
+
+	httpClient := NewHttpClient()
+	ctx, cancel := context.WithCancel(context.Background())
 	hc := HealthCheck{
 		config:     config,
 		authClient: authClient,
 		status: &model.Status{
 			Tasks: make(map[string]*model.Task),
 		},
-		wsClient:   NewGorillaWsClient(ex, authClient),
+		wsClient:   NewWsClient(ex, authClient),
+		sseClient:  NewSseClient(ex, authClient, httpClient),
 		exporter:   ex,
 		watchDog:   wd,
-		httpClient: &http.Client{},
+		httpClient: httpClient,
 		cluster:    cl,
+		restarts:   newRestartTracker(),
+		grpcClient: NewGrpcClient(),
+		redis:      redis.NewRedis(),
+		sqlClient:  NewSqlClient(),
+		history:    hs,
+		store:      st,
+		slo:        sl,
+		triggers:   make(map[string]chan struct{}),
+		ctx:        ctx,
+		cancel:     cancel,
 	}
 
 	hc.Start()
@@ -49,10 +89,15 @@ func NewHealthCheck(config *model.Config, authClient *authentication.AuthClient,
 func (hc *HealthCheck) Start() {
 	for i := range hc.config.Jobs {
 		hc.InitTask(&hc.config.Jobs[i])
+		hc.triggers[hc.config.Jobs[i].Id] = make(chan struct{}, 1)
 	}
 
 	for i := range hc.config.Jobs {
-		go hc.StartTask(&hc.config.Jobs[i])
+		hc.wg.Add(1)
+		go func(function *model.Job) {
+			defer hc.wg.Done()
+			hc.StartTask(function)
+		}(&hc.config.Jobs[i])
 	}
 }
 
@@ -144,6 +189,58 @@ func (hc *HealthCheck) setTaskRestartTime(id string, value int64) {
 	task.RestartTime = value
 }
 
+func (hc *HealthCheck) getTaskIncidentStart(id string) int64 {
+	hc.status.Mx.Lock()
+	defer hc.status.Mx.Unlock()
+
+	task := hc.getTask(id)
+
+	return task.IncidentStart
+}
+
+func (hc *HealthCheck) setTaskResult(id string, result model.CheckResult) {
+	hc.status.Mx.Lock()
+	defer hc.status.Mx.Unlock()
+
+	task := hc.getTask(id)
+	task.State = result.Status
+
+	if len(task.LastResults) == 0 || task.LastResults[len(task.LastResults)-1].Status != result.Status {
+		task.LastStateChange = result.Timestamp
+	}
+	// incident lasts from the first failed check until the job is up again
+	switch result.Status {
+	case common.StatusUp:
+		task.IncidentStart = 0
+	case common.StatusDegraded, common.StatusDown:
+		task.LastFailureReason = result.Reason
+		if task.IncidentStart == 0 {
+			task.IncidentStart = result.Timestamp
+		}
+	}
+
+	summary := hc.config.History.Summary
+	if summary <= 0 {
+		summary = common.DefaultHistorySummary
+	}
+	task.LastResults = append(task.LastResults, result)
+	if len(task.LastResults) > summary {
+		task.LastResults = task.LastResults[len(task.LastResults)-summary:]
+	}
+}
+
+// saveTask persists task state so it survives restart
+func (hc *HealthCheck) saveTask(id string) {
+	hc.status.Mx.Lock()
+	task := *hc.getTask(id)
+	hc.status.Mx.Unlock()
+
+	err := hc.store.Put(common.StoreBucketTasks, id, &task)
+	if err != nil {
+		log.Error(fmt.Sprintf("%s: Failed to save task state: %s", id, err.Error()))
+	}
+}
+
 func (hc *HealthCheck) StartTask(function *model.Job) {
 	log.Info(fmt.Sprintf("Starting task: %s", function.Id))
 	counter := 0
@@ -157,7 +254,11 @@ func (hc *HealthCheck) StartTask(function *model.Job) {
 					active = true
 					break
 				}
-				time.Sleep(time.Duration(1) * time.Second)
+				select {
+				case <-time.After(time.Duration(1) * time.Second):
+				case <-hc.ctx.Done():
+					return
+				}
 			}
 
 		} else {
@@ -165,56 +266,120 @@ func (hc *HealthCheck) StartTask(function *model.Job) {
 		}
 
 		if active {
-			if hc.check(function) {
-				hc.exporter.SetCounter(function.Id, hc.isTaskOnline(function.Id))
-				if hc.isTaskOnline(function.Id) {
-					log.Debug(fmt.Sprintf("%s: Task status updated (is online?): %t",
-						function.Id, hc.getTask(function.Id).Online))
-				}
-
-				hc.setTaskOnline(function.Id, true)
-				hc.setTaskSuccessChecks(function.Id, hc.getTaskSuccessChecks(function.Id)+1)
-				hc.setTaskFailureChecks(function.Id, 0)
-
-				log.Debug(fmt.Sprintf("%s: Task status updated (is online?): %t",
-					function.Id, hc.getTask(function.Id).Online))
-			} else {
-				hc.exporter.AddCounter(function.Id, function.Timeout)
+			hc.handleResult(function, hc.check(function))
+		}
 
-				hc.setTaskOnline(function.Id, false)
-				hc.setTaskFailureChecks(function.Id, hc.getTaskFailureChecks(function.Id)+1)
-				log.Info(fmt.Sprintf("%s: Task status updated (is online?): %t, count: %d",
-					function.Id, hc.getTask(function.Id).Online, hc.getTask(function.Id).FailureChecks))
+		duration := time.Duration(function.Timeout) * time.Second
+		select {
+		case <-time.After(duration):
+		case <-hc.triggers[function.Id]:
+		case <-hc.ctx.Done():
+			log.Info(fmt.Sprintf("Stopping task: %s", function.Id))
+			return
+		}
+	}
+}
 
-				if function.WatchDogAction.Enabled &&
-					hc.getTaskFailureChecks(function.Id) >= function.WatchDogAction.FailureThreshold &&
-					(time.Now().Unix()-hc.getTaskRestartTime(function.Id)) > function.WatchDogAction.AwaitAfterRestart {
+// handleResult records check result and updates task counters. Failures
+// reaching the threshold are sent to watchdog
+func (hc *HealthCheck) handleResult(function *model.Job, result model.CheckResult) {
+	hc.history.Add(function.Id, result)
+	hc.setTaskResult(function.Id, result)
+	hc.slo.Add(function.Id, result)
+	hc.exporter.SetSlo(hc.slo.Report(function))
+	hc.exporter.SetStatus(function.Id, result.Status)
+
+	if result.Status == common.StatusUnknown {
+		// watchdog can't evaluate the check, keep counters untouched
+		log.Warn(fmt.Sprintf("%s: Task status is unknown: %s", function.Id, result.Reason))
+	} else if result.Status == common.StatusDegraded && !isFailure(function, result) {
+		// service responds, but degraded result neither counts toward the failure
+		// threshold nor resets failures of the incident
+		hc.setTaskOnline(function.Id, true)
+		log.Info(fmt.Sprintf("%s: Task status is degraded: %s", function.Id, result.Reason))
+	} else if !isFailure(function, result) {
+		hc.exporter.ResetCounter(function.Id)
+		if hc.isTaskOnline(function.Id) {
+			log.Debug(fmt.Sprintf("%s: Task status updated (is online?): %t",
+				function.Id, hc.getTask(function.Id).Online))
+		}
 
-					log.Info(fmt.Sprintf("Task %s is sent to watchdog", function.Id))
-					hc.watchDog.Execute(function.WatchDogAction.Actions)
+		hc.setTaskOnline(function.Id, true)
+		hc.setTaskSuccessChecks(function.Id, hc.getTaskSuccessChecks(function.Id)+1)
+		hc.setTaskFailureChecks(function.Id, 0)
 
-					hc.exporter.IncWatchdogActionCounter(function.Id)
+		log.Debug(fmt.Sprintf("%s: Task status updated (is online?): %t",
+			function.Id, hc.getTask(function.Id).Online))
+	} else {
+		hc.exporter.AddCounter(function.Id, function.Timeout)
+
+		hc.setTaskOnline(function.Id, result.Status != common.StatusDown)
+		hc.setTaskFailureChecks(function.Id, hc.getTaskFailureChecks(function.Id)+1)
+		log.Info(fmt.Sprintf("%s: Task status updated (is online?): %t, count: %d",
+			function.Id, hc.getTask(function.Id).Online, hc.getTask(function.Id).FailureChecks))
+
+		// await after restart of the job or the last execution of its actions,
+		// e.g. by another job sharing the action
+		restartTime := max(hc.getTaskRestartTime(function.Id), hc.watchDog.LastActionTime(function.WatchDogAction.Actions))
+		if function.WatchDogAction.Enabled &&
+			hc.getTaskFailureChecks(function.Id) >= function.WatchDogAction.FailureThreshold &&
+			(time.Now().Unix()-restartTime) > function.WatchDogAction.AwaitAfterRestart {
+
+			log.Info(fmt.Sprintf("Task %s is sent to watchdog, incident started %ds ago", function.Id,
+				time.Now().Unix()-hc.getTaskIncidentStart(function.Id)))
+			hc.watchDog.Execute(function.WatchDogAction.Actions)
+
+			hc.exporter.IncWatchdogActionCounter(function.Id)
+
+			// for y := 0; y < len(function.WatchDog.Deployments); y++ {
+			// 	err := hc.watchDog.DeletePod(function.WatchDog.Deployments[y], function.WatchDog.Namespace)
+			// 	if err != nil {
+			// 		log.Error(fmt.Sprintf("Delete pod error: %s", err.Error()))
+			// 	}
+			// }
+
+			hc.setTaskFailureChecks(function.Id, 0)
+			hc.setTaskRestartTime(function.Id, time.Now().Unix())
+		}
+	}
 
-					// for y := 0; y < len(function.WatchDog.Deployments); y++ {
-					// 	err := hc.watchDog.DeletePod(function.WatchDog.Deployments[y], function.WatchDog.Namespace)
-					// 	if err != nil {
-					// 		log.Error(fmt.Sprintf("Delete pod error: %s", err.Error()))
-					// 	}
-					// }
+	hc.saveTask(function.Id)
+}
 
-					hc.setTaskFailureChecks(function.Id, 0)
-					hc.setTaskRestartTime(function.Id, time.Now().Unix())
-				}
-			}
-		}
+// trigger runs the next check of the job right away, e.g. on status change
+// received from a stream
+func (hc *HealthCheck) trigger(id string) {
+	select {
+	case hc.triggers[id] <- struct{}{}:
+	default:
+	}
+}
 
-		duration := time.Duration(function.Timeout) * time.Second
-		time.Sleep(duration)
+// isFailure reports whether result counts toward watchdog failure threshold
+func isFailure(function *model.Job, result model.CheckResult) bool {
+	switch result.Status {
+	case common.StatusDown:
+		return true
+	case common.StatusDegraded:
+		return function.WatchDogAction.OnDegraded
 	}
+
+	return false
 }
 
 func (hc *HealthCheck) InitTask(function *model.Job) {
 	task := hc.getTask(function.Id)
+
+	// restore state saved before restart
+	found, err := hc.store.Get(common.StoreBucketTasks, function.Id, task)
+	if err != nil {
+		log.Error(fmt.Sprintf("%s: Failed to restore task state: %s", function.Id, err.Error()))
+	} else if found {
+		log.Info(fmt.Sprintf("%s: Restored task state, failure checks: %d, restart time: %d, incident start: %d",
+			task.Id, task.FailureChecks, task.RestartTime, task.IncidentStart))
+	}
+	task.Id = function.Id
+
 	log.Info(fmt.Sprintf("Initialized task: %s", task.Id))
 
 	// if function.Location.Type == "kubernetes" {
@@ -243,156 +408,236 @@ func (hc *HealthCheck) InitTask(function *model.Job) {
 	// }
 }
 
-func (hc *HealthCheck) check(function *model.Job) bool {
+// check runs the job checker and converts its outcome into a check result
+func (hc *HealthCheck) check(function *model.Job) model.CheckResult {
+	start := time.Now()
+
+	var err error
+	var output string
+	// latency measured by the checker, e.g. reply time, replaces check duration
+	var latency time.Duration
 	switch function.Type {
-	case "http_get":
-		return hc.checkHttpGet(function)
-	case "http_post":
-		return hc.checkHttpPost(function)
+	case "http", "http_get", "http_post":
+		err = hc.checkHttp(function)
 	case "websocket":
-		return hc.checkWs(function)
+		latency, err = hc.checkWs(function)
+	case "sse":
+		err = hc.checkSse(function)
 	case "memory":
-		return hc.checkMemory(function)
+		err = hc.checkMemory(function)
+	case "resources":
+		err = hc.checkResources(function)
+	case "kubernetes":
+		err = hc.checkKubernetes(function)
+	case "cronjob":
+		err = hc.checkCronJob(function)
+	case "logs":
+		output, err = hc.checkLogs(function)
+	case "promql":
+		err = hc.checkPromql(function)
+	case "tcp":
+		err = hc.checkTcp(function)
+	case "dns":
+		err = hc.checkDns(function)
+	case "tls":
+		err = hc.checkTls(function)
+	case "grpc":
+		err = hc.checkGrpc(function)
+	case "redis":
+		err = hc.checkRedis(function)
+	case "sql":
+		err = hc.checkSql(function)
+	case "exec":
+		output, err = hc.checkExec(function)
+	case "scenario":
+		err = hc.checkScenario(function)
+	default:
+		err = fmt.Errorf("unsupported job type %s", function.Type)
 	}
 
-	return false
+	result := model.CheckResult{
+		Timestamp: start.Unix(),
+		Status:    common.StatusUp,
+		Latency:   time.Since(start).Milliseconds(),
+		Output:    output,
+	}
+	if latency > 0 {
+		result.Latency = latency.Milliseconds()
+	}
+
+	// evaluate response time thresholds
+	if err == nil && function.DownAbove > 0 && result.Latency > function.DownAbove {
+		err = fmt.Errorf("response time %dms exceeded %dms", result.Latency, function.DownAbove)
+	}
+	if err == nil && function.DegradedAbove > 0 && result.Latency > function.DegradedAbove {
+		result.Status = common.StatusDegraded
+		result.Reason = fmt.Sprintf("response time %dms exceeded %dms", result.Latency, function.DegradedAbove)
+		log.Warn(fmt.Sprintf("%s: %s", function.Id, result.Reason))
+	}
+
+	var assertionErr *AssertionError
+	if errors.As(err, &assertionErr) {
+		result.Failures = assertionErr.Failures
+	}
+	var stepErr *StepError
+	if errors.As(err, &stepErr) {
+		result.Step = stepErr.Step
+	}
+
+	switch {
+	case err == nil:
+	case isDegraded(err):
+		log.Warn(fmt.Sprintf("%s: %s", function.Id, err.Error()))
+		result.Status = common.StatusDegraded
+		result.Reason = err.Error()
+	case isUnknown(err):
+		log.Error(fmt.Sprintf("%s: %s", function.Id, err.Error()))
+		result.Status = common.StatusUnknown
+		result.Reason = err.Error()
+	default:
+		log.Error(fmt.Sprintf("%s: %s", function.Id, err.Error()))
+		result.Status = common.StatusDown
+		result.Reason = err.Error()
+	}
+
+	return result
 }
 
-func (hc *HealthCheck) checkMemory(function *model.Job) bool {
+func (hc *HealthCheck) checkMemory(function *model.Job) error {
+	if hc.cluster == nil {
+		return unknown(errors.New("cluster is not configured"))
+	}
+
 	podsMemory, err := hc.cluster.GetPodMemory(function.Label, function.Namespace)
 	if err != nil {
-		return false
+		return unknown(fmt.Errorf("metrics api: %w", err))
 	}
 
 	for i := 0; i < len(podsMemory); i++ {
 		if podsMemory[i] > function.Limit {
-			log.Error(fmt.Sprintf("Memory usage: %d higher than expected: %d", podsMemory[i], function.Limit))
-			return false
+			return fmt.Errorf("memory usage: %d higher than expected: %d", podsMemory[i], function.Limit)
 		}
 	}
 
-	return true
+	return nil
 }
 
-func (hc *HealthCheck) checkWs(function *model.Job) bool {
+// checkWs checks connections of each url. Request mode returns total reply time
+func (hc *HealthCheck) checkWs(function *model.Job) (time.Duration, error) {
+	var latency time.Duration
 	for _, u := range function.Urls {
-		difference := hc.wsClient.TimeDifferenceWithLastMessage(function.Id, u, function.ResponseTimeout)
+		connection := hc.wsClient.getConnection(function, u)
+		hc.exporter.SetMessageAge(function.Id, u, connection.messageAge().Seconds())
+
+		var err error
+		switch function.Websocket.Mode {
+		case "", common.ModeStream:
+			err = checkFreshness("wss", &connection.streamState)
+		case common.ModeRequest:
+			var reply time.Duration
+			reply, err = hc.checkWsRequest(function, connection, u)
+			latency += reply
+		case common.ModeThroughput:
+			err = hc.checkThroughput("wss", &connection.streamState, function.Websocket.MinMessages)
+		default:
+			err = unknown(fmt.Errorf("unsupported websocket mode %s", function.Websocket.Mode))
+		}
+		if err != nil {
+			return 0, err
+		}
 
-		if difference > function.Timeout {
-			log.Error(fmt.Sprintf("%s: error wss last message exceeded timeout", function.Id))
-			return false
+		if function.Tls.Enabled && strings.HasPrefix(u, "wss://") {
+			if err := hc.probeTls(function, u); err != nil {
+				return 0, err
+			}
 		}
 	}
 
-	return true
+	return latency, nil
 }
 
-func (hc *HealthCheck) getHttpClient(function *model.Job) *http.Client {
-	if function.AuthEnabled {
-		return hc.authClient.GetClient()
-	} else {
-		return hc.httpClient
+// checkWsRequest measures time until reply to request message. Latency
+// thresholds of the job apply to the reply time
+func (hc *HealthCheck) checkWsRequest(function *model.Job, connection *WsConnection, u string) (time.Duration, error) {
+	timeout := time.Duration(function.Websocket.ReplyTimeout) * time.Second
+	if timeout <= 0 {
+		timeout = common.DefaultCheckTimeout * time.Second
 	}
-}
-
-func (hc *HealthCheck) checkHttpGet(function *model.Job) bool {
-	start := time.Now()
-
-	for _, u := range function.Urls {
-		req, err := http.NewRequest("GET", u, nil)
-		if err != nil {
-			return false
-		}
 
-		if function.ResponseTimeout > 0 {
-			ctx, cancel := context.WithTimeout(context.Background(), time.Duration(function.ResponseTimeout)*time.Second)
-			req = req.WithContext(ctx)
-			defer cancel()
-		}
-
-		resp, err := hc.getHttpClient(function).Do(req)
-		if err != nil {
-			log.Error(fmt.Sprintf("Error http get request: %s", err.Error()))
-			return false
-		}
-		if resp == nil || resp.StatusCode != 200 {
-			log.Error(fmt.Sprintf("%s: Empty http get result or invalid response code", function.Id))
-			return false
-		}
-		defer resp.Body.Close()
+	latency, err := hc.wsClient.request(connection, timeout)
+	if err != nil {
+		return 0, connectionFailure(err, fmt.Errorf("wss request on url %s: %s", u, err.Error()))
 	}
+	hc.exporter.SetGauge(function.Id, float64(latency.Milliseconds()))
+	log.Info(fmt.Sprintf("%s %s", function.Id, latency))
 
-	hc.exporter.SetGauge(function.Id, float64(time.Since(start).Milliseconds()))
-	log.Info(fmt.Sprintf("%s %s", function.Id, time.Since(start)))
-
-	return true
+	return latency, nil
 }
 
-func (hc *HealthCheck) checkHttpPost(function *model.Job) bool {
-	for _, u := range function.Urls {
-		req, err := http.NewRequest("POST", u, strings.NewReader(function.Body))
-		if err != nil {
-			return false
-		}
-
-		req.Header.Add("accept", "*/*")
-		req.Header.Add("Content-Type", "application/json")
+// Close stops tasks and background connections of the jobs and waits for
+// running checks, so the state is not saved after the store is closed
+func (hc *HealthCheck) Close() {
+	hc.cancel()
+	hc.wsClient.Close()
+	hc.sseClient.Close()
+	hc.grpcClient.Close()
+	hc.wg.Wait()
+}
 
-		if function.ResponseTimeout > 0 {
-			ctx, cancel := context.WithTimeout(context.Background(), time.Duration(function.ResponseTimeout)*time.Second)
-			req = req.WithContext(ctx)
-			defer func() {
-				cancel()
-			}()
-		}
+// Status returns copy of tasks status taken under lock, so it can be encoded
+// while checks update the status
+func (hc *HealthCheck) Status() (*model.Status, error) {
+	hc.status.Mx.Lock()
+	defer hc.status.Mx.Unlock()
 
-		resp, err := hc.getHttpClient(function).Do(req)
-		if resp != nil {
-			defer check(resp)
-			defer cleanup(resp)
-		}
-		if err != nil {
-			log.Error(fmt.Sprintf("Error http post request on url %s: %s", u, err.Error()))
-			return false
-		}
-		if resp == nil {
-			log.Error(fmt.Sprintf("Empty http post result on url %s", u))
-			return false
-		}
-		if resp.StatusCode != 200 {
-			log.Error(fmt.Sprintf("Invalid response code %d on url %s", resp.StatusCode, u))
-			return false
-		}
+	status := &model.Status{Tasks: make(map[string]*model.Task, len(hc.status.Tasks))}
+	for id, task := range hc.status.Tasks {
+		t := *task
+		t.LastResults = append([]model.CheckResult(nil), task.LastResults...)
+		status.Tasks[id] = &t
 	}
 
-	return true
+	return status, nil
 }
 
-// should read body to avoid memory leak
-func cleanup(resp *http.Response) {
-	defer resp.Body.Close()
-	if resp.Body != nil {
-		_, err := io.Copy(io.Discard, resp.Body)
-		if err != nil {
-			log.Error(fmt.Sprintf("Error while read body: %s", err.Error()))
+func (hc *HealthCheck) getJob(id string) *model.Job {
+	for i := range hc.config.Jobs {
+		if hc.config.Jobs[i].Id == id {
+			return &hc.config.Jobs[i]
 		}
 	}
+
+	return nil
 }
 
-func check(resp *http.Response) {
-	if !resp.Close {
-		log.Trace("Response is not closed")
+// History returns check results of the job since given time (unix seconds)
+func (hc *HealthCheck) History(id string, since int64, limit int) ([]model.CheckResult, error) {
+	if hc.getJob(id) == nil {
+		return nil, ErrJobNotFound
 	}
 
-	if !resp.Request.Close {
-		log.Trace("Request is not closed")
-	}
+	return hc.history.Get(id, since, limit), nil
 }
 
-func (hc *HealthCheck) Status() (*model.Status, error) {
-	return hc.status, nil
+// Slo returns availability and error budget of the job
+func (hc *HealthCheck) Slo(id string) (*model.SloReport, error) {
+	job := hc.getJob(id)
+	if job == nil {
+		return nil, ErrJobNotFound
+	}
+
+	return hc.slo.Report(job), nil
 }
 
 func (hc *HealthCheck) Ready() error {
 	return hc.cluster.Test()
 }
+
+// checkTimeout returns job response timeout or default timeout of checks
+func checkTimeout(function *model.Job) time.Duration {
+	if function.ResponseTimeout > 0 {
+		return time.Duration(function.ResponseTimeout) * time.Second
+	}
+
+	return common.DefaultCheckTimeout * time.Second
+}
@@ -0,0 +1,296 @@
+package healthcheck
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/healthcheck-watchdog/cmd/common"
+	"github.com/healthcheck-watchdog/cmd/exporter"
+	"github.com/healthcheck-watchdog/cmd/history"
+	"github.com/healthcheck-watchdog/cmd/model"
+	"github.com/healthcheck-watchdog/cmd/slo"
+	"github.com/healthcheck-watchdog/cmd/store"
+	"github.com/healthcheck-watchdog/cmd/watchdog"
+)
+
+// newTestHealthCheck returns healthcheck with in-memory state and a watchdog
+// without effective actions, checks are not started
+func newTestHealthCheck(jobs ...model.Job) *HealthCheck {
+	return restartTestHealthCheck(store.NewMemoryStore(), jobs...)
+}
+
+// restartTestHealthCheck returns healthcheck restoring state of the store
+func restartTestHealthCheck(st store.Store, jobs ...model.Job) *HealthCheck {
+	config := &model.Config{
+		Jobs:     jobs,
+		WatchDog: model.WatchDog{Actions: []model.Action{{Id: "noop"}}},
+	}
+
+	hc := &HealthCheck{
+		config:     config,
+		status:     &model.Status{Tasks: make(map[string]*model.Task)},
+		exporter:   &exporter.Exporter{},
+		watchDog:   watchdog.NewWatchDog(nil, config, st),
+		httpClient: NewHttpClient(),
+		history:    history.NewHistory(config),
+		store:      st,
+		slo:        slo.NewTracker(config, st),
+	}
+	for i := range config.Jobs {
+		hc.InitTask(&config.Jobs[i])
+	}
+
+	return hc
+}
+
+func TestLatencyThresholds(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(50 * time.Millisecond)
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name          string
+		degradedAbove int64
+		downAbove     int64
+		status        string
+		reason        string
+	}{
+		{name: "below thresholds", degradedAbove: 5000, downAbove: 10000, status: common.StatusUp},
+		{name: "degraded", degradedAbove: 10, downAbove: 10000, status: common.StatusDegraded, reason: "exceeded 10ms"},
+		{name: "down", degradedAbove: 10, downAbove: 20, status: common.StatusDown, reason: "exceeded 20ms"},
+		{name: "down without degraded", downAbove: 20, status: common.StatusDown, reason: "exceeded 20ms"},
+	}
+	for _, tt := range tests {
+		function := model.Job{Id: "h", Type: "http", Urls: []string{server.URL}, DegradedAbove: tt.degradedAbove, DownAbove: tt.downAbove}
+		hc := newTestHealthCheck(function)
+
+		result := hc.check(&function)
+		if result.Status != tt.status || !strings.Contains(result.Reason, tt.reason) {
+			t.Errorf("%s: expected %s %q, got %s %q", tt.name, tt.status, tt.reason, result.Status, result.Reason)
+		}
+		if result.Latency < 50 {
+			t.Errorf("%s: expected latency of at least 50ms, got %d", tt.name, result.Latency)
+		}
+	}
+}
+
+func TestDegradedFailureThreshold(t *testing.T) {
+	degraded := model.CheckResult{Status: common.StatusDegraded, Reason: "slow"}
+
+	tests := []struct {
+		name       string
+		onDegraded bool
+		// failure checks after each of three degraded results
+		failures []int
+		online   bool
+		restart  bool
+	}{
+		{name: "degraded ignored", failures: []int{0, 0, 0}, online: true},
+		{name: "degraded counted", onDegraded: true, failures: []int{1, 2, 0}, online: true, restart: true},
+	}
+	for _, tt := range tests {
+		function := model.Job{Id: "d", Type: "http", WatchDogAction: model.WatchDogAction{
+			Enabled: true, Actions: []string{"noop"}, FailureThreshold: 3, OnDegraded: tt.onDegraded}}
+		hc := newTestHealthCheck(function)
+
+		for i, expected := range tt.failures {
+			degraded.Timestamp = time.Now().Unix()
+			hc.handleResult(&function, degraded)
+			if failures := hc.getTaskFailureChecks(function.Id); failures != expected {
+				t.Errorf("%s: expected %d failure checks after check %d, got %d", tt.name, expected, i+1, failures)
+			}
+		}
+
+		task := hc.getTask(function.Id)
+		if task.Online != tt.online || (task.RestartTime > 0) != tt.restart || task.State != common.StatusDegraded {
+			t.Errorf("%s: expected online %t restart %t, got %+v", tt.name, tt.online, tt.restart, task)
+		}
+	}
+}
+
+func TestDownFailureThreshold(t *testing.T) {
+	function := model.Job{Id: "d", Type: "http", WatchDogAction: model.WatchDogAction{
+		Enabled: true, Actions: []string{"noop"}, FailureThreshold: 2, AwaitAfterRestart: 3600}}
+	hc := newTestHealthCheck(function)
+
+	down := model.CheckResult{Timestamp: time.Now().Unix(), Status: common.StatusDown, Reason: "refused"}
+	for i, expected := range []int{1, 0, 1, 2, 3} {
+		hc.handleResult(&function, down)
+		if failures := hc.getTaskFailureChecks(function.Id); failures != expected {
+			t.Errorf("expected %d failure checks after check %d, got %d", expected, i+1, failures)
+		}
+	}
+	if hc.isTaskOnline(function.Id) {
+		t.Error("expected task offline")
+	}
+
+	// the next success resets failures, the saved task keeps restart time
+	hc.handleResult(&function, model.CheckResult{Timestamp: time.Now().Unix(), Status: common.StatusUp})
+	saved := &model.Task{}
+	if found, _ := hc.store.Get(common.StoreBucketTasks, function.Id, saved); !found ||
+		saved.FailureChecks != 0 || saved.SuccessChecks != 1 || !saved.Online || saved.RestartTime == 0 {
+		t.Errorf("unexpected saved task %+v", saved)
+	}
+}
+
+func TestDegradedKeepsFailures(t *testing.T) {
+	function := model.Job{Id: "d", Type: "http", WatchDogAction: model.WatchDogAction{
+		Enabled: true, Actions: []string{"noop"}, FailureThreshold: 2}}
+	hc := newTestHealthCheck(function)
+
+	now := time.Now().Unix()
+	hc.handleResult(&function, model.CheckResult{Timestamp: now, Status: common.StatusDown, Reason: "refused"})
+	hc.handleResult(&function, model.CheckResult{Timestamp: now, Status: common.StatusDegraded, Reason: "slow"})
+	task := hc.getTask(function.Id)
+	if task.FailureChecks != 1 || task.SuccessChecks != 0 || !task.Online || task.RestartTime != 0 {
+		t.Errorf("expected failures kept on degraded, got %+v", task)
+	}
+
+	// degraded result between failures doesn't delay the action
+	hc.handleResult(&function, model.CheckResult{Timestamp: now, Status: common.StatusDown, Reason: "refused"})
+	if task := hc.getTask(function.Id); task.RestartTime == 0 || task.FailureChecks != 0 || task.SuccessChecks != 0 {
+		t.Errorf("expected action on the second down, got %+v", task)
+	}
+}
+
+func TestUnknownResultKeepsCounters(t *testing.T) {
+	function := model.Job{Id: "u", Type: "http", WatchDogAction: model.WatchDogAction{
+		Enabled: true, Actions: []string{"noop"}, FailureThreshold: 3}}
+	hc := newTestHealthCheck(function)
+
+	now := time.Now().Unix()
+	hc.handleResult(&function, model.CheckResult{Timestamp: now, Status: common.StatusUp})
+	hc.handleResult(&function, model.CheckResult{Timestamp: now, Status: common.StatusDown, Reason: "refused"})
+	hc.handleResult(&function, model.CheckResult{Timestamp: now, Status: common.StatusDown, Reason: "refused"})
+	before := *hc.getTask(function.Id)
+	sloBefore := hc.slo.Report(&function)
+
+	// unknown results never reach the failure threshold
+	for i := 0; i < 5; i++ {
+		hc.handleResult(&function, model.CheckResult{Timestamp: now, Status: common.StatusUnknown, Reason: "token endpoint unavailable"})
+	}
+
+	task := hc.getTask(function.Id)
+	if task.FailureChecks != before.FailureChecks || task.SuccessChecks != before.SuccessChecks ||
+		task.Online != before.Online || task.RestartTime != 0 {
+		t.Errorf("expected counters untouched, got %+v, before %+v", task, before)
+	}
+	if task.State != common.StatusUnknown || task.LastFailureReason != "refused" {
+		t.Errorf("expected unknown state with last failure reason kept, got %+v", task)
+	}
+	if availability := hc.slo.Report(&function).Availability["1h"]; availability != sloBefore.Availability["1h"] {
+		t.Errorf("expected slo untouched, got availability %v, before %v", availability, sloBefore.Availability["1h"])
+	}
+	if results := hc.history.Get(function.Id, 0, 0); len(results) != 8 {
+		t.Errorf("expected unknown results in history, got %d results", len(results))
+	}
+}
+
+func TestIncidentStateRestored(t *testing.T) {
+	function := model.Job{Id: "i", Type: "http"}
+	st := store.NewMemoryStore()
+	hc := restartTestHealthCheck(st, function)
+
+	hc.handleResult(&function, model.CheckResult{Timestamp: 100, Status: common.StatusUp})
+	hc.handleResult(&function, model.CheckResult{Timestamp: 200, Status: common.StatusDegraded, Reason: "slow"})
+	hc.handleResult(&function, model.CheckResult{Timestamp: 300, Status: common.StatusDown, Reason: "refused"})
+	hc.handleResult(&function, model.CheckResult{Timestamp: 400, Status: common.StatusUnknown})
+	if start := hc.getTaskIncidentStart(function.Id); start != 200 {
+		t.Errorf("expected incident started on the first failed check, got %d", start)
+	}
+
+	// incident and counters survive restart
+	hc = restartTestHealthCheck(st, function)
+	task := hc.getTask(function.Id)
+	if task.IncidentStart != 200 || task.FailureChecks != 1 || task.LastFailureReason != "refused" {
+		t.Errorf("expected incident state restored, got %+v", task)
+	}
+
+	hc.handleResult(&function, model.CheckResult{Timestamp: 500, Status: common.StatusUp})
+	if start := hc.getTaskIncidentStart(function.Id); start != 0 {
+		t.Errorf("expected incident closed, got %d", start)
+	}
+}
+
+func TestSharedActionAwait(t *testing.T) {
+	action := model.WatchDogAction{Enabled: true, Actions: []string{"noop"}, FailureThreshold: 1, AwaitAfterRestart: 3600}
+	first := model.Job{Id: "first", Type: "http", WatchDogAction: action}
+	second := model.Job{Id: "second", Type: "http", WatchDogAction: action}
+	st := store.NewMemoryStore()
+	hc := restartTestHealthCheck(st, first, second)
+
+	down := model.CheckResult{Timestamp: time.Now().Unix(), Status: common.StatusDown, Reason: "refused"}
+	hc.handleResult(&first, down)
+	if restart := hc.getTaskRestartTime(first.Id); restart == 0 {
+		t.Fatal("expected action executed for the first job")
+	}
+
+	// the action executed for the first job is awaited by the second one, also after restart
+	hc = restartTestHealthCheck(st, first, second)
+	hc.handleResult(&second, down)
+	if restart := hc.getTaskRestartTime(second.Id); restart != 0 || hc.getTaskFailureChecks(second.Id) != 1 {
+		t.Errorf("expected action awaited, got restart time %d", restart)
+	}
+}
+
+// closingStore fails the test on writes after the store is closed
+type closingStore struct {
+	store.Store
+	t      *testing.T
+	mx     sync.Mutex
+	closed bool
+	puts   int
+}
+
+func (s *closingStore) Put(bucket string, key string, value interface{}) error {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	if s.closed {
+		s.t.Errorf("%s/%s saved after the store is closed", bucket, key)
+	}
+	s.puts++
+
+	return s.Store.Put(bucket, key, value)
+}
+
+func (s *closingStore) Close() error {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	s.closed = true
+
+	return s.Store.Close()
+}
+
+func TestCloseWaitsForChecks(t *testing.T) {
+	started := make(chan struct{}, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case started <- struct{}{}:
+		default:
+		}
+		time.Sleep(300 * time.Millisecond)
+	}))
+	defer server.Close()
+
+	config := &model.Config{Jobs: []model.Job{
+		{Id: "close", Type: "http", Urls: []string{server.URL}, Timeout: 1},
+		// dependent job waits for the first one to be online
+		{Id: "dependent", Type: "http", Urls: []string{server.URL}, Timeout: 1, DependentJob: "missing"},
+	}}
+	st := &closingStore{Store: store.NewMemoryStore(), t: t}
+	hc := NewHealthCheck(config, nil, &exporter.Exporter{}, watchdog.NewWatchDog(nil, config, st), nil,
+		history.NewHistory(config), st, slo.NewTracker(config, st))
+
+	<-started
+	hc.Close()
+	st.Close()
+	if st.puts == 0 {
+		t.Error("expected running check saved before close returned")
+	}
+	time.Sleep(500 * time.Millisecond)
+}
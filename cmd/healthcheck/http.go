@@ -0,0 +1,317 @@
+package healthcheck
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/healthcheck-watchdog/cmd/common"
+	"github.com/healthcheck-watchdog/cmd/model"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/net/http2"
+)
+
+// HttpClient keeps http clients of jobs built from their request configuration
+type HttpClient struct {
+	mx      sync.Mutex
+	clients map[string]*http.Client
+}
+
+func NewHttpClient() *HttpClient {
+	return &HttpClient{
+		clients: make(map[string]*http.Client),
+	}
+}
+
+func (hcl *HttpClient) getClient(function *model.Job) (*http.Client, error) {
+	hcl.mx.Lock()
+	defer hcl.mx.Unlock()
+
+	if client, found := hcl.clients[function.Id]; found {
+		return client, nil
+	}
+
+	config := &function.Request
+
+	// ca bundle of tls inspection is trusted by requests as well
+	caFile := config.CaFile
+	if caFile == "" {
+		caFile = function.Tls.CaFile
+	}
+	pool, err := loadCaFile(caFile)
+	if err != nil {
+		return nil, unknown(err)
+	}
+
+	tlsConfig := &tls.Config{
+		RootCAs:            pool,
+		ServerName:         config.ServerName,
+		InsecureSkipVerify: config.InsecureSkipVerify, //nolint:gosec // opt-in by configuration
+	}
+
+	if config.HttpVersion == common.HttpVersion2 {
+		if config.Proxy != "" {
+			return nil, unknown(fmt.Errorf("proxy is not supported with http version %s", config.HttpVersion))
+		}
+		client := &http.Client{
+			Transport:     newHttp2Transport(tlsConfig),
+			CheckRedirect: redirectPolicy(config),
+		}
+		hcl.clients[function.Id] = client
+
+		return client, nil
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	transport.DisableKeepAlives = config.DisableKeepAlives
+	switch config.HttpVersion {
+	case "":
+	case common.HttpVersion1:
+		// empty TLSNextProto disables HTTP/2 negotiation
+		transport.ForceAttemptHTTP2 = false
+		transport.TLSNextProto = make(map[string]func(string, *tls.Conn) http.RoundTripper)
+	default:
+		return nil, unknown(fmt.Errorf("unsupported http version %s", config.HttpVersion))
+	}
+	if config.Proxy != "" {
+		proxy, err := url.Parse(config.Proxy)
+		if err != nil {
+			return nil, unknown(fmt.Errorf("invalid proxy %s: %w", config.Proxy, err))
+		}
+		transport.Proxy = http.ProxyURL(proxy)
+	}
+
+	client := &http.Client{
+		Transport:     transport,
+		CheckRedirect: redirectPolicy(config),
+	}
+	hcl.clients[function.Id] = client
+
+	return client, nil
+}
+
+// http2Transport sends HTTP/2 requests without HTTP/1.1 fallback: negotiated
+// over tls on https urls and with prior knowledge (h2c) on http urls
+type http2Transport struct {
+	tls   *http2.Transport
+	plain *http2.Transport
+}
+
+func newHttp2Transport(tlsConfig *tls.Config) *http2Transport {
+	return &http2Transport{
+		tls: &http2.Transport{TLSClientConfig: tlsConfig},
+		plain: &http2.Transport{
+			AllowHTTP: true,
+			DialTLSContext: func(ctx context.Context, network string, addr string, _ *tls.Config) (net.Conn, error) {
+				var dialer net.Dialer
+				return dialer.DialContext(ctx, network, addr)
+			},
+		},
+	}
+}
+
+func (t *http2Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.Scheme == "http" {
+		return t.plain.RoundTrip(req)
+	}
+
+	return t.tls.RoundTrip(req)
+}
+
+// redirectPolicy follows up to maxRedirects redirects unless followRedirects is disabled.
+// Not followed redirect response is evaluated by assertions. With serverName redirects
+// are followed within the configured host only, certificate of another host would be
+// verified for serverName
+func redirectPolicy(config *model.Request) func(*http.Request, []*http.Request) error {
+	if config.FollowRedirects != nil && !*config.FollowRedirects {
+		return func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		}
+	}
+
+	limit := config.MaxRedirects
+	if limit <= 0 {
+		limit = common.DefaultMaxRedirects
+	}
+
+	return func(req *http.Request, via []*http.Request) error {
+		if len(via) >= limit {
+			return fmt.Errorf("stopped after %d redirects", limit)
+		}
+		if config.ServerName != "" && req.URL.Host != via[0].URL.Host {
+			return fmt.Errorf("redirect to another host %s with serverName %s", req.URL.Host, config.ServerName)
+		}
+		return nil
+	}
+}
+
+// checkHttp sends configured request to each url and evaluates the response.
+// http_get and http_post are the same check with GET and json POST defaults
+func (hc *HealthCheck) checkHttp(function *model.Job) error {
+	client, err := hc.httpClient.getClient(function)
+	if err != nil {
+		return err
+	}
+
+	start := time.Now()
+	for _, u := range function.Urls {
+		if err := hc.checkHttpUrl(function, client, u); err != nil {
+			return err
+		}
+	}
+
+	hc.exporter.SetGauge(function.Id, float64(time.Since(start).Milliseconds()))
+	log.Info(fmt.Sprintf("%s %s", function.Id, time.Since(start)))
+
+	return nil
+}
+
+func (hc *HealthCheck) checkHttpUrl(function *model.Job, client *http.Client, u string) error {
+	req, err := newHttpRequest(function, &function.Request, u, nil)
+	if err != nil {
+		return unknown(err)
+	}
+
+	if err := hc.authorize(function, req); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), checkTimeout(function))
+	defer cancel()
+	req = req.WithContext(ctx)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("http %s request on url %s: %s", strings.ToLower(req.Method), u, err.Error())
+	}
+	defer cleanup(resp)
+
+	if _, err := checkResponse(function.Assertions, u, resp); err != nil {
+		return err
+	}
+	// chain and hostname are verified on handshake unless it skipped verification
+	if function.Tls.Enabled && resp.TLS != nil {
+		if err := hc.inspectTls(function, tlsServerName(function, req), resp.TLS, function.Request.InsecureSkipVerify); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// tlsServerName returns name the certificate of the url is verified for
+func tlsServerName(function *model.Job, req *http.Request) string {
+	switch {
+	case function.Tls.ServerName != "":
+		return function.Tls.ServerName
+	case function.Request.ServerName != "":
+		return function.Request.ServerName
+	}
+
+	return req.URL.Hostname()
+}
+
+// authorize adds bearer access token to the request. Token failures are
+// reported as unknown, so they are not counted as failures of the monitored service
+func (hc *HealthCheck) authorize(function *model.Job, req *http.Request) error {
+	if !function.AuthEnabled {
+		return nil
+	}
+
+	token, err := hc.authClient.GetToken()
+	if err != nil {
+		return unknown(fmt.Errorf("access token: %w", err))
+	}
+	req.Header.Set("Authorization", "Bearer "+token.AccessToken)
+
+	return nil
+}
+
+// newHttpRequest builds request from configuration. Url, query, header, form and body
+// values are expanded with ${name} placeholders. Request with body defaults to POST
+func newHttpRequest(function *model.Job, config *model.Request, u string, vars map[string]string) (*http.Request, error) {
+	var body io.Reader
+	contentType := ""
+	switch {
+	case len(config.Form) > 0:
+		form := url.Values{}
+		for key, value := range config.Form {
+			form.Set(key, expand(value, vars))
+		}
+		body = strings.NewReader(form.Encode())
+		contentType = "application/x-www-form-urlencoded"
+	case config.BodyFile != "":
+		data, err := os.ReadFile(config.BodyFile)
+		if err != nil {
+			return nil, fmt.Errorf("read body file: %w", err)
+		}
+		body = bytes.NewReader([]byte(expand(string(data), vars)))
+	case config.Body != "":
+		body = strings.NewReader(expand(config.Body, vars))
+	case function.Type == "http_post":
+		body = strings.NewReader(function.Body)
+	}
+	// http_post sends json unless form or Content-Type header is configured
+	if function.Type == "http_post" && contentType == "" && body != nil {
+		contentType = "application/json"
+	}
+
+	method := strings.ToUpper(config.Method)
+	if method == "" {
+		method = http.MethodGet
+		if body != nil {
+			method = http.MethodPost
+		}
+	}
+
+	req, err := http.NewRequest(method, expand(u, vars), body)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(config.Query) > 0 {
+		query := req.URL.Query()
+		for key, value := range config.Query {
+			query.Set(key, expand(value, vars))
+		}
+		req.URL.RawQuery = query.Encode()
+	}
+
+	if function.Type == "http_post" {
+		req.Header.Set("accept", "*/*")
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	for key, value := range config.Headers {
+		if strings.EqualFold(key, "Host") {
+			req.Host = expand(value, vars)
+			continue
+		}
+		req.Header.Set(key, expand(value, vars))
+	}
+
+	return req, nil
+}
+
+// should read body to avoid memory leak. Large bodies are read up to DrainLimit,
+// the connection is closed then
+func cleanup(resp *http.Response) {
+	defer resp.Body.Close()
+	if resp.Body != nil {
+		_, err := io.CopyN(io.Discard, resp.Body, common.DrainLimit)
+		if err != nil && err != io.EOF {
+			log.Error(fmt.Sprintf("Error while read body: %s", err.Error()))
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package healthcheck
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/healthcheck-watchdog/cmd/model"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+)
+
+func TestHttpVersion(t *testing.T) {
+	proto := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Proto)) //nolint:errcheck // test server
+	})
+	server := httptest.NewUnstartedServer(proto)
+	server.EnableHTTP2 = true
+	server.StartTLS()
+	defer server.Close()
+	http1 := httptest.NewTLSServer(proto)
+	defer http1.Close()
+	plain := httptest.NewServer(h2c.NewHandler(proto, &http2.Server{}))
+	defer plain.Close()
+
+	tests := []struct {
+		name     string
+		version  string
+		url      string
+		expected string
+		failure  string
+	}{
+		{name: "1.1", version: "1.1", url: server.URL, expected: "HTTP/1.1"},
+		{name: "2", version: "2", url: server.URL, expected: "HTTP/2.0"},
+		{name: "2 without tls", version: "2", url: plain.URL, expected: "HTTP/2.0"},
+		// no fallback to HTTP/1.1 when server doesn't negotiate HTTP/2
+		{name: "2 on http1 server", version: "2", url: http1.URL, failure: "no application protocol"},
+	}
+	for _, tt := range tests {
+		function := &model.Job{Id: "http-" + tt.name, Request: model.Request{InsecureSkipVerify: true, HttpVersion: tt.version}}
+		client, err := NewHttpClient().getClient(function)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := client.Get(tt.url)
+		if tt.failure != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.failure) {
+				t.Errorf("%s: expected %q, got %v", tt.name, tt.failure, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		cleanup(resp)
+		if resp.Proto != tt.expected {
+			t.Errorf("%s: expected %s, got %s", tt.name, tt.expected, resp.Proto)
+		}
+	}
+
+	if _, err := NewHttpClient().getClient(&model.Job{Id: "http-3", Request: model.Request{HttpVersion: "3"}}); !isUnknown(err) {
+		t.Errorf("expected unknown for unsupported version, got %v", err)
+	}
+}
+
+func TestHttpPostContentType(t *testing.T) {
+	tests := []struct {
+		name     string
+		request  model.Request
+		expected string
+	}{
+		{name: "default body", expected: "application/json"},
+		{name: "request body", request: model.Request{Body: `{"ping":true}`}, expected: "application/json"},
+		{name: "header", request: model.Request{Body: "ping", Headers: map[string]string{"Content-Type": "text/plain"}}, expected: "text/plain"},
+		{name: "form", request: model.Request{Form: map[string]string{"ping": "1"}}, expected: "application/x-www-form-urlencoded"},
+	}
+	for _, tt := range tests {
+		function := &model.Job{Id: "post", Type: "http_post", Request: tt.request}
+		req, err := newHttpRequest(function, &function.Request, "http://api/ping", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if contentType := req.Header.Get("Content-Type"); contentType != tt.expected || req.Method != http.MethodPost {
+			t.Errorf("%s: expected POST %s, got %s %s", tt.name, tt.expected, req.Method, contentType)
+		}
+	}
+}
+
+func TestServerNameRedirect(t *testing.T) {
+	other := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer other.Close()
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/local":
+			http.Redirect(w, r, "/home", http.StatusFound)
+		case "/other":
+			http.Redirect(w, r, other.URL, http.StatusFound)
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		path    string
+		failure string
+	}{
+		{path: "/local"},
+		{path: "/other", failure: "redirect to another host " + strings.TrimPrefix(other.URL, "https://") + " with serverName example.com"},
+	}
+	for _, tt := range tests {
+		function := &model.Job{Id: "sni" + tt.path, Request: model.Request{InsecureSkipVerify: true, ServerName: "example.com"}}
+		client, err := NewHttpClient().getClient(function)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := client.Get(server.URL + tt.path)
+		if tt.failure == "" {
+			if err != nil {
+				t.Errorf("%s: unexpected error: %v", tt.path, err)
+				continue
+			}
+			cleanup(resp)
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.failure) {
+			t.Errorf("%s: expected %q, got %v", tt.path, tt.failure, err)
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package healthcheck
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/healthcheck-watchdog/cmd/common"
+	"github.com/healthcheck-watchdog/cmd/model"
+)
+
+// restartTracker keeps restart counts of containers of each job to detect
+// restarts within a window
+type restartTracker struct {
+	mx      sync.Mutex
+	samples map[string]map[string][]restartSample
+}
+
+type restartSample struct {
+	time  time.Time
+	count int32
+}
+
+func newRestartTracker() *restartTracker {
+	return &restartTracker{samples: make(map[string]map[string][]restartSample)}
+}
+
+// observe records restart count of the container and returns its increase within the window
+func (t *restartTracker) observe(id string, key string, count int32, window time.Duration) int32 {
+	t.mx.Lock()
+	defer t.mx.Unlock()
+
+	job, found := t.samples[id]
+	if !found {
+		job = make(map[string][]restartSample)
+		t.samples[id] = job
+	}
+
+	now := time.Now()
+	samples := job[key]
+	// restart count is reset when pod is recreated with the same name
+	if len(samples) > 0 && samples[len(samples)-1].count > count {
+		samples = nil
+	}
+	samples = append(samples, restartSample{time: now, count: count})
+
+	from := now.Add(-window)
+	i := 0
+	for i < len(samples)-1 && samples[i].time.Before(from) {
+		i++
+	}
+	samples = samples[i:]
+	job[key] = samples
+
+	return count - samples[0].count
+}
+
+// retain drops containers of the job which are gone
+func (t *restartTracker) retain(id string, seen map[string]bool) {
+	t.mx.Lock()
+	defer t.mx.Unlock()
+
+	for key := range t.samples[id] {
+		if !seen[key] {
+			delete(t.samples[id], key)
+		}
+	}
+}
+
+// checkKubernetes inspects available replicas of workloads, states and restarts
+// of pods and ready endpoints of services. Failure names every unhealthy object
+func (hc *HealthCheck) checkKubernetes(function *model.Job) error {
+	if hc.cluster == nil {
+		return unknown(errors.New("cluster is not configured"))
+	}
+
+	config := &function.Kubernetes
+	failures := make([]string, 0)
+
+	for _, w := range config.Workloads {
+		desired, available, found, err := hc.cluster.GetWorkloadReplicas(w.Kind, w.Name, function.Namespace)
+		if err != nil {
+			return unknown(fmt.Errorf("kube api: %s %s: %w", w.Kind, w.Name, err))
+		}
+		if !found {
+			failures = append(failures, fmt.Sprintf("%s %s not found", w.Kind, w.Name))
+		} else if available < desired {
+			failures = append(failures, fmt.Sprintf("%s %s: %d of %d replicas available", w.Kind, w.Name, available, desired))
+		}
+	}
+
+	selector := config.Selector
+	if selector == "" && function.Label != "" {
+		selector = fmt.Sprintf("app=%s", function.Label)
+	}
+	// all pods of namespace are checked without workloads and services
+	if selector != "" || (len(config.Workloads) == 0 && len(config.Services) == 0) {
+		podFailures, err := hc.checkPods(function, selector)
+		if err != nil {
+			return err
+		}
+		failures = append(failures, podFailures...)
+	}
+
+	for _, service := range config.Services {
+		ready, found, err := hc.cluster.GetReadyEndpoints(service, function.Namespace)
+		if err != nil {
+			return unknown(fmt.Errorf("kube api: service %s: %w", service, err))
+		}
+		if !found {
+			failures = append(failures, fmt.Sprintf("service %s not found", service))
+		} else if ready == 0 {
+			failures = append(failures, fmt.Sprintf("service %s has no ready endpoints", service))
+		}
+	}
+
+	if len(failures) > 0 {
+		return errors.New(strings.Join(failures, "; "))
+	}
+
+	return nil
+}
+
+// checkPods finds pods pending too long, containers waiting in crash loop or
+// image pull errors and containers restarted more than maxRestarts within the window
+func (hc *HealthCheck) checkPods(function *model.Job, selector string) ([]string, error) {
+	config := &function.Kubernetes
+
+	pods, err := hc.cluster.GetPodStates(selector, function.Namespace)
+	if err != nil {
+		return nil, unknown(fmt.Errorf("kube api: pods %s: %w", selector, err))
+	}
+
+	window := common.DefaultRestartWindow * time.Second
+	if config.RestartWindow > 0 {
+		window = time.Duration(config.RestartWindow) * time.Second
+	}
+	pendingTimeout := common.DefaultPendingTimeout * time.Second
+	if config.PendingTimeout > 0 {
+		pendingTimeout = time.Duration(config.PendingTimeout) * time.Second
+	}
+
+	failures := make([]string, 0)
+	seen := make(map[string]bool)
+	for _, pod := range pods {
+		if pod.Phase == "Pending" {
+			if pending := time.Since(pod.Created); pending > pendingTimeout {
+				failures = append(failures, fmt.Sprintf("pod %s pending for %s", pod.Name, pending.Round(time.Second)))
+			}
+		}
+
+		for _, c := range pod.Containers {
+			if contains(common.UnhealthyWaitingReasons, c.Waiting) {
+				failures = append(failures, fmt.Sprintf("pod %s container %s: %s", pod.Name, c.Name, c.Waiting))
+			}
+
+			key := pod.Name + "/" + c.Name
+			seen[key] = true
+			if restarts := hc.restarts.observe(function.Id, key, c.Restarts, window); restarts > config.MaxRestarts {
+				failures = append(failures, fmt.Sprintf("pod %s container %s restarted %d times in %s", pod.Name, c.Name, restarts, window))
+			}
+		}
+	}
+	hc.restarts.retain(function.Id, seen)
+
+	return failures, nil
+}
@@ -0,0 +1,190 @@
+package healthcheck
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/healthcheck-watchdog/cmd/cluster"
+	"github.com/healthcheck-watchdog/cmd/model"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func kubernetesHealthCheck(clientset *fake.Clientset) *HealthCheck {
+	return &HealthCheck{
+		cluster:  cluster.NewClusterForClient(clientset, nil),
+		restarts: newRestartTracker(),
+	}
+}
+
+func apiPod(name string, phase corev1.PodPhase, created time.Time, statuses ...corev1.ContainerStatus) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              name,
+			Namespace:         "ns",
+			Labels:            map[string]string{"app": "api"},
+			CreationTimestamp: metav1.NewTime(created),
+		},
+		Status: corev1.PodStatus{Phase: phase, ContainerStatuses: statuses},
+	}
+}
+
+func running(restarts int32) corev1.ContainerStatus {
+	return corev1.ContainerStatus{
+		Name:         "app",
+		RestartCount: restarts,
+		State:        corev1.ContainerState{Running: &corev1.ContainerStateRunning{}},
+	}
+}
+
+func waiting(reason string) corev1.ContainerStatus {
+	return corev1.ContainerStatus{
+		Name:  "app",
+		State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: reason}},
+	}
+}
+
+func assertFailures(t *testing.T, err error, expected ...string) {
+	t.Helper()
+	if len(expected) == 0 {
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return
+	}
+	if err == nil {
+		t.Fatalf("expected failures %q, got nil", expected)
+	}
+	if isUnknown(err) {
+		t.Fatalf("expected down, got unknown: %v", err)
+	}
+	for _, e := range expected {
+		if !strings.Contains(err.Error(), e) {
+			t.Errorf("expected %q in %q", e, err.Error())
+		}
+	}
+}
+
+func TestCheckKubernetesWorkloads(t *testing.T) {
+	three, two := int32(3), int32(2)
+	hc := kubernetesHealthCheck(fake.NewSimpleClientset(
+		&appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: "ns"},
+			Spec:       appsv1.DeploymentSpec{Replicas: &three},
+			Status:     appsv1.DeploymentStatus{AvailableReplicas: 1},
+		},
+		&appsv1.StatefulSet{
+			ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "ns"},
+			Spec:       appsv1.StatefulSetSpec{Replicas: &two},
+			Status:     appsv1.StatefulSetStatus{ReadyReplicas: 2, AvailableReplicas: 2},
+		},
+	))
+
+	function := &model.Job{Id: "k", Namespace: "ns", Kubernetes: model.Kubernetes{
+		Workloads: []model.Workload{{Kind: "deployment", Name: "api"}, {Kind: "statefulset", Name: "db"}, {Kind: "deployment", Name: "gone"}},
+	}}
+	err := hc.checkKubernetes(function)
+	assertFailures(t, err, "deployment api: 1 of 3 replicas available", "deployment gone not found")
+	if strings.Contains(err.Error(), "statefulset db") {
+		t.Errorf("healthy statefulset reported: %v", err)
+	}
+}
+
+func TestCheckKubernetesPodStates(t *testing.T) {
+	now := time.Now()
+	hc := kubernetesHealthCheck(fake.NewSimpleClientset(
+		apiPod("crash", corev1.PodRunning, now, waiting("CrashLoopBackOff")),
+		apiPod("image", corev1.PodPending, now, waiting("ImagePullBackOff")),
+		apiPod("pending", corev1.PodPending, now.Add(-time.Hour)),
+		apiPod("starting", corev1.PodPending, now),
+		apiPod("ok", corev1.PodRunning, now, running(0)),
+	))
+
+	function := &model.Job{Id: "k", Namespace: "ns", Label: "api", Kubernetes: model.Kubernetes{PendingTimeout: 600}}
+	err := hc.checkKubernetes(function)
+	assertFailures(t, err,
+		"pod crash container app: CrashLoopBackOff",
+		"pod image container app: ImagePullBackOff",
+		"pod pending pending for")
+	for _, healthy := range []string{"pod starting", "pod ok"} {
+		if strings.Contains(err.Error(), healthy) {
+			t.Errorf("healthy %s reported: %v", healthy, err)
+		}
+	}
+}
+
+func TestCheckKubernetesRestarts(t *testing.T) {
+	clientset := fake.NewSimpleClientset(apiPod("api-1", corev1.PodRunning, time.Now(), running(5)))
+	hc := kubernetesHealthCheck(clientset)
+	function := &model.Job{Id: "k", Namespace: "ns", Label: "api", Kubernetes: model.Kubernetes{MaxRestarts: 1, RestartWindow: 600}}
+
+	setRestarts := func(restarts int32) {
+		pod := apiPod("api-1", corev1.PodRunning, time.Now(), running(restarts))
+		if _, err := clientset.CoreV1().Pods("ns").Update(context.Background(), pod, metav1.UpdateOptions{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// restarts before the first check are not counted
+	assertFailures(t, hc.checkKubernetes(function))
+
+	setRestarts(6)
+	assertFailures(t, hc.checkKubernetes(function))
+
+	setRestarts(8)
+	assertFailures(t, hc.checkKubernetes(function), "pod api-1 container app restarted 3 times in 10m0s")
+
+	// recreated pod with the same name starts counting again
+	setRestarts(0)
+	assertFailures(t, hc.checkKubernetes(function))
+
+	setRestarts(1)
+	assertFailures(t, hc.checkKubernetes(function))
+}
+
+func TestRestartTrackerWindow(t *testing.T) {
+	tracker := newRestartTracker()
+	tracker.samples["k"] = map[string][]restartSample{
+		"api-1/app": {{time: time.Now().Add(-time.Hour), count: 1}, {time: time.Now().Add(-time.Minute), count: 4}},
+	}
+
+	// sample outside of the window is dropped
+	if restarts := tracker.observe("k", "api-1/app", 5, 10*time.Minute); restarts != 1 {
+		t.Errorf("expected 1 restart within window, got %d", restarts)
+	}
+
+	tracker.retain("k", map[string]bool{})
+	if len(tracker.samples["k"]) != 0 {
+		t.Errorf("expected gone containers dropped, got %v", tracker.samples["k"])
+	}
+}
+
+func TestCheckKubernetesServices(t *testing.T) {
+	hc := kubernetesHealthCheck(fake.NewSimpleClientset(
+		&corev1.Endpoints{
+			ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: "ns"},
+			Subsets:    []corev1.EndpointSubset{{Addresses: []corev1.EndpointAddress{{IP: "10.0.0.1"}}}},
+		},
+		&corev1.Endpoints{
+			ObjectMeta: metav1.ObjectMeta{Name: "empty", Namespace: "ns"},
+		},
+	))
+
+	function := &model.Job{Id: "k", Namespace: "ns", Kubernetes: model.Kubernetes{Services: []string{"api", "empty", "missing"}}}
+	err := hc.checkKubernetes(function)
+	assertFailures(t, err, "service empty has no ready endpoints", "service missing not found")
+	if strings.Contains(err.Error(), "service api ") {
+		t.Errorf("healthy service reported: %v", err)
+	}
+}
+
+func TestCheckKubernetesWithoutCluster(t *testing.T) {
+	hc := &HealthCheck{restarts: newRestartTracker()}
+	if err := hc.checkKubernetes(&model.Job{Id: "k"}); !isUnknown(err) {
+		t.Errorf("expected unknown, got %v", err)
+	}
+}
@@ -0,0 +1,110 @@
+package healthcheck
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/healthcheck-watchdog/cmd/common"
+	"github.com/healthcheck-watchdog/cmd/model"
+	log "github.com/sirupsen/logrus"
+)
+
+// logPattern is compiled pattern with its matches during the window
+type logPattern struct {
+	name    string
+	re      *regexp.Regexp
+	max     int
+	count   int
+	pods    map[string]int
+	samples []string
+}
+
+// checkLogs counts log lines of pods matching each pattern within the window.
+// Pattern matched more than max times fails the job, its sample lines are returned as output
+func (hc *HealthCheck) checkLogs(function *model.Job) (string, error) {
+	if hc.cluster == nil {
+		return "", unknown(errors.New("cluster is not configured"))
+	}
+
+	config := &function.Logs
+	if len(config.Patterns) == 0 {
+		return "", unknown(errors.New("missing log patterns"))
+	}
+
+	patterns := make([]*logPattern, 0, len(config.Patterns))
+	for _, p := range config.Patterns {
+		re, err := regexp.Compile(p.Regex)
+		if err != nil {
+			return "", unknown(fmt.Errorf("invalid log pattern %s: %w", p.Regex, err))
+		}
+		name := p.Name
+		if name == "" {
+			name = p.Regex
+		}
+		patterns = append(patterns, &logPattern{name: name, re: re, max: p.Max, pods: make(map[string]int)})
+	}
+
+	selector := config.Selector
+	if selector == "" {
+		selector = fmt.Sprintf("app=%s", function.Label)
+	}
+	window := common.DefaultLogWindow * time.Second
+	if config.Window > 0 {
+		window = time.Duration(config.Window) * time.Second
+	}
+	samples := common.DefaultLogSamples
+	if config.Samples > 0 {
+		samples = config.Samples
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), checkTimeout(function))
+	defer cancel()
+
+	err := hc.cluster.StreamPodLogs(ctx, selector, function.Namespace, config.Container, window, func(pod string, container string, line string) {
+		for _, p := range patterns {
+			if !p.re.MatchString(line) {
+				continue
+			}
+			p.count++
+			p.pods[pod]++
+			if len(p.samples) < samples {
+				p.samples = append(p.samples, fmt.Sprintf("%s/%s: %s", pod, container, truncate(line)))
+			}
+		}
+	})
+	if err != nil {
+		return "", unknown(fmt.Errorf("kube api: %w", err))
+	}
+	if ctx.Err() != nil {
+		log.Warn(fmt.Sprintf("%s: logs are read partially in %s", function.Id, checkTimeout(function)))
+	}
+
+	failures := make([]string, 0)
+	output := make([]string, 0)
+	for _, p := range patterns {
+		hc.exporter.SetLogWindowMatches(function.Id, p.name, p.count)
+		if p.count <= p.max {
+			continue
+		}
+
+		pods := make([]string, 0, len(p.pods))
+		for pod, count := range p.pods {
+			pods = append(pods, fmt.Sprintf("%s: %d", pod, count))
+		}
+		sort.Strings(pods)
+		failures = append(failures, fmt.Sprintf("pattern %s matched %d lines in %s, max %d (%s)",
+			p.name, p.count, window, p.max, strings.Join(pods, ", ")))
+		output = append(output, p.samples...)
+	}
+
+	if len(failures) > 0 {
+		return strings.Join(output, "\n"), errors.New(strings.Join(failures, "; "))
+	}
+
+	return "", nil
+}
@@ -0,0 +1,208 @@
+package healthcheck
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/healthcheck-watchdog/cmd/common"
+	"github.com/healthcheck-watchdog/cmd/model"
+)
+
+// promqlResponse is response of prometheus instant query API
+type promqlResponse struct {
+	Status    string `json:"status"`
+	ErrorType string `json:"errorType"`
+	Error     string `json:"error"`
+	Data      struct {
+		ResultType string          `json:"resultType"`
+		Result     json.RawMessage `json:"result"`
+	} `json:"data"`
+}
+
+type promqlSample struct {
+	Metric map[string]string `json:"metric"`
+	Value  [2]interface{}    `json:"value"`
+}
+
+// checkPromql runs instant query and compares value of each series with down
+// and degraded thresholds. Prometheus and query failures and NaN values are
+// reported as unknown
+func (hc *HealthCheck) checkPromql(function *model.Job) error {
+	config := &function.Promql
+	values, err := hc.queryPromql(function)
+	if err != nil {
+		return err
+	}
+	hc.exporter.SetPromqlValues(function.Id, values)
+
+	if len(values) == 0 {
+		err := fmt.Errorf("promql query %s returned empty result", config.Query)
+		switch config.OnEmpty {
+		case common.StatusUp:
+			return nil
+		case common.StatusDown:
+			return err
+		default:
+			return unknown(err)
+		}
+	}
+
+	series := make([]string, 0, len(values))
+	for s := range values {
+		series = append(series, s)
+	}
+	sort.Strings(series)
+
+	down := make([]string, 0)
+	warn := make([]string, 0)
+	nan := make([]string, 0)
+	for _, s := range series {
+		// NaN is not comparable with thresholds, e.g. ratio of zero rates
+		if math.IsNaN(values[s]) {
+			nan = append(nan, s)
+		} else if failure := promqlCompare(s, values[s], config.Min, config.Max); failure != "" {
+			down = append(down, failure)
+		} else if failure := promqlCompare(s, values[s], config.DegradedMin, config.DegradedMax); failure != "" {
+			warn = append(warn, failure)
+		}
+	}
+
+	if len(down) > 0 {
+		return errors.New(strings.Join(down, "; "))
+	}
+	if len(nan) > 0 {
+		return unknown(fmt.Errorf("promql query %s returned NaN for series %s", config.Query, strings.Join(nan, ", ")))
+	}
+	if len(warn) > 0 {
+		return degraded(errors.New(strings.Join(warn, "; ")))
+	}
+
+	return nil
+}
+
+// queryPromql returns value of each series of vector or scalar result
+func (hc *HealthCheck) queryPromql(function *model.Job) (map[string]float64, error) {
+	config := &function.Promql
+
+	client, err := hc.httpClient.getClient(function)
+	if err != nil {
+		return nil, unknown(err)
+	}
+
+	request := function.Request
+	request.Method = http.MethodGet
+	request.Query = map[string]string{"query": config.Query}
+	for key, value := range function.Request.Query {
+		request.Query[key] = value
+	}
+	u := strings.TrimSuffix(config.Url, "/") + "/api/v1/query"
+	req, err := newHttpRequest(function, &request, u, nil)
+	if err != nil {
+		return nil, unknown(err)
+	}
+	if config.Username != "" {
+		req.SetBasicAuth(expand(config.Username, nil), expand(config.Password, nil))
+	}
+	if err := hc.authorize(function, req); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), checkTimeout(function))
+	defer cancel()
+	req = req.WithContext(ctx)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, unknown(fmt.Errorf("prometheus %s: %s", config.Url, err.Error()))
+	}
+	defer cleanup(resp)
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, unknown(fmt.Errorf("prometheus %s: %s", config.Url, err.Error()))
+	}
+
+	var response promqlResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, unknown(fmt.Errorf("prometheus %s: invalid response code %d: %s", config.Url, resp.StatusCode, truncate(strings.TrimSpace(string(body)))))
+	}
+	if response.Status != "success" {
+		return nil, unknown(fmt.Errorf("prometheus %s: %s: %s", config.Url, response.ErrorType, response.Error))
+	}
+
+	values := make(map[string]float64)
+	switch response.Data.ResultType {
+	case common.PromqlVector:
+		var samples []promqlSample
+		if err := json.Unmarshal(response.Data.Result, &samples); err != nil {
+			return nil, unknown(fmt.Errorf("prometheus %s: invalid vector: %s", config.Url, err.Error()))
+		}
+		for _, sample := range samples {
+			value, err := promqlValue(sample.Value)
+			if err != nil {
+				return nil, unknown(err)
+			}
+			values[promqlSeries(sample.Metric)] = value
+		}
+	case common.PromqlScalar:
+		var sample [2]interface{}
+		if err := json.Unmarshal(response.Data.Result, &sample); err != nil {
+			return nil, unknown(fmt.Errorf("prometheus %s: invalid scalar: %s", config.Url, err.Error()))
+		}
+		value, err := promqlValue(sample)
+		if err != nil {
+			return nil, unknown(err)
+		}
+		values["{}"] = value
+	default:
+		return nil, unknown(fmt.Errorf("unsupported promql result type %s", response.Data.ResultType))
+	}
+
+	return values, nil
+}
+
+// promqlValue parses [timestamp, "value"] pair
+func promqlValue(sample [2]interface{}) (float64, error) {
+	text, ok := sample[1].(string)
+	if !ok {
+		return 0, fmt.Errorf("invalid promql value %v", sample[1])
+	}
+
+	return strconv.ParseFloat(text, 64)
+}
+
+// promqlSeries formats labels of series as {name="value",...} sorted by name
+func promqlSeries(metric map[string]string) string {
+	names := make([]string, 0, len(metric))
+	for name := range metric {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	labels := make([]string, 0, len(names))
+	for _, name := range names {
+		labels = append(labels, fmt.Sprintf("%s=%q", name, metric[name]))
+	}
+
+	return "{" + strings.Join(labels, ",") + "}"
+}
+
+// promqlCompare returns failure of value outside of min and max, empty string otherwise
+func promqlCompare(series string, value float64, min *float64, max *float64) string {
+	if min != nil && value < *min {
+		return fmt.Sprintf("series %s value %g below %g", series, value, *min)
+	}
+	if max != nil && value > *max {
+		return fmt.Sprintf("series %s value %g above %g", series, value, *max)
+	}
+
+	return ""
+}
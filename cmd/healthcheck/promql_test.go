@@ -0,0 +1,91 @@
+package healthcheck
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/healthcheck-watchdog/cmd/common"
+	"github.com/healthcheck-watchdog/cmd/exporter"
+	"github.com/healthcheck-watchdog/cmd/model"
+)
+
+// promqlServer responds to each query with the response of the map
+func promqlServer(t *testing.T, responses map[string]string) string {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/query" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		response, found := responses[r.URL.Query().Get("query")]
+		if !found {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"status":"error","errorType":"bad_data","error":"parse error"}`)) //nolint:errcheck // test server
+			return
+		}
+		w.Write([]byte(response)) //nolint:errcheck // test server
+	}))
+	t.Cleanup(server.Close)
+
+	return server.URL
+}
+
+func TestCheckPromql(t *testing.T) {
+	u := promqlServer(t, map[string]string{
+		"up": `{"status":"success","data":{"resultType":"vector","result":[
+			{"metric":{"job":"api","instance":"a"},"value":[1700000000,"1"]},
+			{"metric":{"job":"api","instance":"b"},"value":[1700000000,"0"]}]}}`,
+		"ratio":   `{"status":"success","data":{"resultType":"scalar","result":[1700000000,"0.75"]}}`,
+		"empty":   `{"status":"success","data":{"resultType":"vector","result":[]}}`,
+		"nan":     `{"status":"success","data":{"resultType":"vector","result":[{"metric":{"job":"api"},"value":[1700000000,"NaN"]}]}}`,
+		"matrix":  `{"status":"success","data":{"resultType":"matrix","result":[]}}`,
+		"garbage": `not json`,
+	})
+	one, half, ninety := 1.0, 0.5, 0.9
+
+	tests := []struct {
+		name     string
+		promql   model.Promql
+		status   string
+		contains string
+	}{
+		{name: "vector below min", promql: model.Promql{Query: "up", Min: &one}, status: common.StatusDown,
+			contains: `series {instance="b",job="api"} value 0 below 1`},
+		{name: "vector ok", promql: model.Promql{Query: "up", Max: &one}, status: common.StatusUp},
+		{name: "scalar ok", promql: model.Promql{Query: "ratio", Min: &half}, status: common.StatusUp},
+		{name: "scalar degraded", promql: model.Promql{Query: "ratio", Min: &half, DegradedMin: &ninety}, status: common.StatusDegraded,
+			contains: "series {} value 0.75 below 0.9"},
+		{name: "empty", promql: model.Promql{Query: "empty"}, status: common.StatusUnknown, contains: "returned empty result"},
+		{name: "empty down", promql: model.Promql{Query: "empty", OnEmpty: common.StatusDown}, status: common.StatusDown},
+		{name: "empty up", promql: model.Promql{Query: "empty", OnEmpty: common.StatusUp}, status: common.StatusUp},
+		{name: "nan", promql: model.Promql{Query: "nan", Min: &one}, status: common.StatusUnknown, contains: `returned NaN for series {job="api"}`},
+		{name: "query error", promql: model.Promql{Query: "bad("}, status: common.StatusUnknown, contains: "bad_data: parse error"},
+		{name: "unsupported type", promql: model.Promql{Query: "matrix"}, status: common.StatusUnknown, contains: "unsupported promql result type matrix"},
+		{name: "invalid response", promql: model.Promql{Query: "garbage"}, status: common.StatusUnknown, contains: "invalid response code 200"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hc := &HealthCheck{exporter: &exporter.Exporter{}, httpClient: NewHttpClient()}
+			tt.promql.Url = u
+			err := hc.checkPromql(&model.Job{Id: "p", Promql: tt.promql})
+
+			status := common.StatusDown
+			switch {
+			case err == nil:
+				status = common.StatusUp
+			case isUnknown(err):
+				status = common.StatusUnknown
+			case isDegraded(err):
+				status = common.StatusDegraded
+			}
+			if status != tt.status {
+				t.Fatalf("expected %s, got %s: %v", tt.status, status, err)
+			}
+			if tt.contains != "" && !strings.Contains(err.Error(), tt.contains) {
+				t.Errorf("expected %q in %q", tt.contains, err.Error())
+			}
+		})
+	}
+}
@@ -0,0 +1,137 @@
+package healthcheck
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	rediscli "github.com/go-redis/redis/v8"
+	"github.com/healthcheck-watchdog/cmd/model"
+	"github.com/healthcheck-watchdog/cmd/redis"
+	log "github.com/sirupsen/logrus"
+)
+
+// checkRedis pings redis and evaluates configured INFO fields, queue lengths and key freshness
+func (hc *HealthCheck) checkRedis(function *model.Job) error {
+	timeout := checkTimeout(function)
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	client, err := hc.redis.Client(function.Id, function.Urls, &function.Redis)
+	if err != nil {
+		return unknown(err)
+	}
+
+	start := time.Now()
+	if err := client.Ping(ctx).Err(); err != nil {
+		return fmt.Errorf("redis ping: %s", err.Error())
+	}
+	hc.exporter.SetGauge(function.Id, float64(time.Since(start).Milliseconds()))
+	log.Info(fmt.Sprintf("%s %s", function.Id, time.Since(start)))
+
+	if len(function.Redis.Info) > 0 {
+		info, err := redis.Info(ctx, client)
+		if err != nil {
+			return fmt.Errorf("redis info: %s", err.Error())
+		}
+		for i := range function.Redis.Info {
+			if err := checkRedisInfo(info, &function.Redis.Info[i]); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, queue := range function.Redis.Queues {
+		length, err := redis.Len(ctx, client, queue.Key, queue.Type)
+		if err != nil {
+			return fmt.Errorf("redis queue %s length: %s", queue.Key, err.Error())
+		}
+		// zero max length only checks that queue can be read
+		if queue.MaxLength > 0 && length > queue.MaxLength {
+			return fmt.Errorf("redis queue %s length %d exceeded %d", queue.Key, length, queue.MaxLength)
+		}
+	}
+
+	for _, key := range function.Redis.Keys {
+		value, err := client.Get(ctx, key.Key).Result()
+		if errors.Is(err, rediscli.Nil) {
+			return fmt.Errorf("redis key %s is missing", key.Key)
+		}
+		if err != nil {
+			return fmt.Errorf("redis key %s: %s", key.Key, err.Error())
+		}
+
+		timestamp, err := parseTimestamp(value)
+		if err != nil {
+			return fmt.Errorf("redis key %s: %s", key.Key, err.Error())
+		}
+		// zero max age only checks that key holds a timestamp
+		if age := time.Since(timestamp); key.MaxAge > 0 && age > time.Duration(key.MaxAge)*time.Second {
+			return fmt.Errorf("redis key %s is %s old, expected at most %ds", key.Key, age.Round(time.Second), key.MaxAge)
+		}
+	}
+
+	return nil
+}
+
+// checkRedisInfo compares INFO field with thresholds. Nested values like
+// slave0:ip=...,lag=1 are addressed as slave0.lag
+func checkRedisInfo(info map[string]string, threshold *model.RedisInfo) error {
+	field, sub, nested := strings.Cut(threshold.Field, ".")
+	value, found := info[field]
+	if found && nested {
+		found = false
+		for _, pair := range strings.Split(value, ",") {
+			if k, v, ok := strings.Cut(pair, "="); ok && k == sub {
+				value, found = v, true
+				break
+			}
+		}
+	}
+	if !found {
+		return fmt.Errorf("redis info field %s is missing", threshold.Field)
+	}
+
+	if threshold.Equals != "" && value != threshold.Equals {
+		return fmt.Errorf("redis info field %s is %s, expected %s", threshold.Field, value, threshold.Equals)
+	}
+
+	if threshold.Min == nil && threshold.Max == nil {
+		return nil
+	}
+
+	number, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return fmt.Errorf("redis info field %s is not a number: %s", threshold.Field, value)
+	}
+	if threshold.Min != nil && number < *threshold.Min {
+		return fmt.Errorf("redis info field %s is %s, expected at least %g", threshold.Field, value, *threshold.Min)
+	}
+	if threshold.Max != nil && number > *threshold.Max {
+		return fmt.Errorf("redis info field %s is %s, expected at most %g", threshold.Field, value, *threshold.Max)
+	}
+
+	return nil
+}
+
+// parseTimestamp accepts unix seconds, unix milliseconds or RFC3339 time
+func parseTimestamp(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if number, err := strconv.ParseFloat(value, 64); err == nil {
+		// values beyond year 2286 in seconds are treated as milliseconds
+		if number > 1e10 {
+			return time.UnixMilli(int64(number)), nil
+		}
+		return time.Unix(0, int64(number*float64(time.Second))), nil
+	}
+
+	timestamp, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid timestamp %q", value)
+	}
+
+	return timestamp, nil
+}
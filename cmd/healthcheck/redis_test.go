@@ -0,0 +1,77 @@
+package healthcheck
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/healthcheck-watchdog/cmd/exporter"
+	"github.com/healthcheck-watchdog/cmd/model"
+	"github.com/healthcheck-watchdog/cmd/redis"
+)
+
+func TestCheckRedis(t *testing.T) {
+	server := miniredis.RunT(t)
+	server.Lpush("jobs", "1")                                                         //nolint:errcheck // test data
+	server.Lpush("jobs", "2")                                                         //nolint:errcheck // test data
+	server.Set("heartbeat", strconv.FormatInt(time.Now().Add(-time.Hour).Unix(), 10)) //nolint:errcheck // test data
+
+	tests := []struct {
+		name    string
+		redis   model.Redis
+		failure string
+	}{
+		{name: "ping", redis: model.Redis{}},
+		{name: "queue", redis: model.Redis{Queues: []model.RedisQueue{{Key: "jobs", MaxLength: 1}}}, failure: "redis queue jobs length 2 exceeded 1"},
+		{name: "queue without limit", redis: model.Redis{Queues: []model.RedisQueue{{Key: "jobs"}}}},
+		{name: "key", redis: model.Redis{Keys: []model.RedisKey{{Key: "heartbeat", MaxAge: 60}}}, failure: "expected at most 60s"},
+		{name: "key without limit", redis: model.Redis{Keys: []model.RedisKey{{Key: "heartbeat"}}}},
+		{name: "missing key", redis: model.Redis{Keys: []model.RedisKey{{Key: "gone"}}}, failure: "redis key gone is missing"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hc := &HealthCheck{exporter: &exporter.Exporter{}, redis: redis.NewRedis()}
+			err := hc.checkRedis(&model.Job{Id: "r", Urls: []string{server.Addr()}, Redis: tt.redis})
+			if tt.failure == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.failure) {
+				t.Errorf("expected %q, got %v", tt.failure, err)
+			}
+		})
+	}
+}
+
+func TestCheckRedisMode(t *testing.T) {
+	hc := &HealthCheck{exporter: &exporter.Exporter{}, redis: redis.NewRedis()}
+	err := hc.checkRedis(&model.Job{Id: "r", Urls: []string{"127.0.0.1:1", "127.0.0.1:2"}})
+	if !isUnknown(err) || !strings.Contains(err.Error(), "set mode to cluster or sentinel") {
+		t.Errorf("expected unknown for several standalone addresses, got %v", err)
+	}
+
+	tests := []struct {
+		redis model.Redis
+		urls  int
+		valid bool
+	}{
+		{redis: model.Redis{}, urls: 1, valid: true},
+		{redis: model.Redis{}, urls: 0},
+		{redis: model.Redis{SentinelMaster: "mymaster"}, urls: 3, valid: true},
+		{redis: model.Redis{Mode: "sentinel"}, urls: 3},
+		{redis: model.Redis{Mode: "cluster"}, urls: 3, valid: true},
+		{redis: model.Redis{Mode: "replica"}, urls: 1},
+		{redis: model.Redis{Queues: []model.RedisQueue{{Key: "jobs"}, {Key: "events", Type: "stream"}}}, urls: 1, valid: true},
+		{redis: model.Redis{Queues: []model.RedisQueue{{Key: "jobs", Type: "set"}}}, urls: 1},
+	}
+	for _, tt := range tests {
+		urls := make([]string, tt.urls)
+		if err := redis.Validate(urls, &tt.redis); (err == nil) != tt.valid {
+			t.Errorf("%+v with %d urls: valid %v, got %v", tt.redis, tt.urls, tt.valid, err)
+		}
+	}
+}
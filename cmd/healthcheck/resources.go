@@ -0,0 +1,165 @@
+package healthcheck
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/healthcheck-watchdog/cmd/cluster"
+	"github.com/healthcheck-watchdog/cmd/common"
+	"github.com/healthcheck-watchdog/cmd/model"
+	log "github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// resourceUsage is usage of a single resource of a container in millicores or bytes
+type resourceUsage struct {
+	name    string
+	usage   int64
+	request int64
+	limit   int64
+}
+
+// checkResources compares cpu and memory usage of containers of pods matching
+// selector with absolute thresholds or percentage of container requests or limits
+func (hc *HealthCheck) checkResources(function *model.Job) error {
+	if hc.cluster == nil {
+		return unknown(errors.New("cluster is not configured"))
+	}
+
+	config := &function.Resources
+	selector := config.Selector
+	if selector == "" {
+		selector = fmt.Sprintf("app=%s", function.Label)
+	}
+
+	cpuMax, err := resourceMax(common.ResourceCpu, config.Cpu)
+	if err != nil {
+		return unknown(err)
+	}
+	memoryMax, err := resourceMax(common.ResourceMemory, config.Memory)
+	if err != nil {
+		return unknown(err)
+	}
+	// job limit is memory threshold in bytes without memory settings
+	if memoryMax < 0 && config.Memory.MaxPercent == 0 && function.Limit > 0 {
+		memoryMax = function.Limit
+	}
+
+	containers, err := hc.cluster.GetPodResources(selector, function.Namespace)
+	if err != nil {
+		return unknown(fmt.Errorf("metrics api: %w", err))
+	}
+
+	hc.exporter.ResetResources(function.Id)
+	pods := make(map[string][2]int64)
+	failures := make([]string, 0)
+	for i := range containers {
+		c := &containers[i]
+		if len(config.Containers) > 0 && !contains(config.Containers, c.Container) {
+			continue
+		}
+
+		usages := []resourceUsage{
+			{name: common.ResourceCpu, usage: c.CpuUsage, request: c.CpuRequest, limit: c.CpuLimit},
+			{name: common.ResourceMemory, usage: c.MemoryUsage, request: c.MemoryRequest, limit: c.MemoryLimit},
+		}
+		thresholds := []model.ResourceThreshold{config.Cpu, config.Memory}
+		maxValues := []int64{cpuMax, memoryMax}
+		for j, u := range usages {
+			hc.exporter.SetContainerUsage(function.Id, c.Pod, c.Container, u.name, resourceValue(u.name, u.usage))
+			if failure := hc.checkResource(function, c, u, thresholds[j], maxValues[j]); failure != "" {
+				failures = append(failures, failure)
+			}
+		}
+
+		pod := pods[c.Pod]
+		pod[0] += c.CpuUsage
+		pod[1] += c.MemoryUsage
+		pods[c.Pod] = pod
+	}
+
+	if len(pods) == 0 {
+		return unknown(fmt.Errorf("no metrics of containers of pods %s in namespace %s", selector, function.Namespace))
+	}
+	for name, pod := range pods {
+		hc.exporter.SetPodUsage(function.Id, name, common.ResourceCpu, resourceValue(common.ResourceCpu, pod[0]))
+		hc.exporter.SetPodUsage(function.Id, name, common.ResourceMemory, resourceValue(common.ResourceMemory, pod[1]))
+	}
+
+	if len(failures) > 0 {
+		return errors.New(strings.Join(failures, "; "))
+	}
+
+	return nil
+}
+
+// checkResource returns failure of container resource usage above absolute
+// max or percentage of requests or limits, empty string otherwise
+func (hc *HealthCheck) checkResource(function *model.Job, c *cluster.ContainerResources, u resourceUsage, threshold model.ResourceThreshold, max int64) string {
+	if max >= 0 && u.usage > max {
+		return fmt.Sprintf("pod %s container %s %s usage %s higher than %s",
+			c.Pod, c.Container, u.name, resourceString(u.name, u.usage), resourceString(u.name, max))
+	}
+
+	of := threshold.Of
+	if of == "" {
+		of = common.ResourceLimits
+	}
+	base := u.limit
+	if of == common.ResourceRequests {
+		base = u.request
+	}
+	if base <= 0 {
+		if threshold.MaxPercent > 0 {
+			log.Debug(fmt.Sprintf("%s: pod %s container %s has no %s %s", function.Id, c.Pod, c.Container, u.name, of))
+		}
+		return ""
+	}
+
+	percent := float64(u.usage) * 100 / float64(base)
+	hc.exporter.SetContainerUsagePercent(function.Id, c.Pod, c.Container, u.name, of, percent)
+	if threshold.MaxPercent > 0 && percent > threshold.MaxPercent {
+		return fmt.Sprintf("pod %s container %s %s usage %.1f%% of %s %s higher than %.1f%%",
+			c.Pod, c.Container, u.name, percent, of, resourceString(u.name, base), threshold.MaxPercent)
+	}
+
+	return ""
+}
+
+// resourceMax parses absolute threshold into millicores or bytes, -1 without threshold
+func resourceMax(name string, threshold model.ResourceThreshold) (int64, error) {
+	if threshold.Of != "" && threshold.Of != common.ResourceLimits && threshold.Of != common.ResourceRequests {
+		return 0, fmt.Errorf("unsupported %s threshold of %s", name, threshold.Of)
+	}
+	if threshold.Max == "" {
+		return -1, nil
+	}
+
+	quantity, err := resource.ParseQuantity(threshold.Max)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s max %s: %s", name, threshold.Max, err.Error())
+	}
+	if name == common.ResourceCpu {
+		return quantity.MilliValue(), nil
+	}
+
+	return quantity.Value(), nil
+}
+
+// resourceValue converts millicores to cores, bytes are kept
+func resourceValue(name string, value int64) float64 {
+	if name == common.ResourceCpu {
+		return float64(value) / 1000
+	}
+
+	return float64(value)
+}
+
+func resourceString(name string, value int64) string {
+	if name == common.ResourceCpu {
+		return resource.NewMilliQuantity(value, resource.DecimalSI).String()
+	}
+
+	return resource.NewQuantity(value, resource.BinarySI).String()
+}
@@ -0,0 +1,159 @@
+package healthcheck
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/healthcheck-watchdog/cmd/cluster"
+	"github.com/healthcheck-watchdog/cmd/exporter"
+	"github.com/healthcheck-watchdog/cmd/model"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	metricsv1beta1 "k8s.io/metrics/pkg/apis/metrics/v1beta1"
+	metricsfake "k8s.io/metrics/pkg/client/clientset/versioned/fake"
+)
+
+func resourceList(cpu string, memory string) corev1.ResourceList {
+	list := corev1.ResourceList{}
+	if cpu != "" {
+		list[corev1.ResourceCPU] = resource.MustParse(cpu)
+	}
+	if memory != "" {
+		list[corev1.ResourceMemory] = resource.MustParse(memory)
+	}
+
+	return list
+}
+
+// resourcesHealthCheck serves pod api-1 with app and sidecar containers, app
+// has requests and limits, sidecar has none
+func resourcesHealthCheck(usage map[string]corev1.ResourceList) *HealthCheck {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "api-1", Namespace: "ns", Labels: map[string]string{"app": "api"}},
+		Spec: corev1.PodSpec{Containers: []corev1.Container{
+			{Name: "app", Resources: corev1.ResourceRequirements{
+				Requests: resourceList("250m", "128Mi"),
+				Limits:   resourceList("1", "256Mi"),
+			}},
+			{Name: "sidecar"},
+		}},
+	}
+
+	podMetrics := &metricsv1beta1.PodMetricsList{}
+	if len(usage) > 0 {
+		item := metricsv1beta1.PodMetrics{ObjectMeta: metav1.ObjectMeta{Name: "api-1", Namespace: "ns", Labels: pod.Labels}}
+		for _, name := range []string{"app", "sidecar"} {
+			item.Containers = append(item.Containers, metricsv1beta1.ContainerMetrics{Name: name, Usage: usage[name]})
+		}
+		podMetrics.Items = append(podMetrics.Items, item)
+	}
+	// fake tracker doesn't map PodMetrics kind to pods resource of metrics API
+	metricsClient := &metricsfake.Clientset{}
+	metricsClient.AddReactor("list", "pods", func(k8stesting.Action) (bool, runtime.Object, error) {
+		return true, podMetrics, nil
+	})
+
+	return &HealthCheck{
+		exporter: &exporter.Exporter{},
+		cluster:  cluster.NewClusterForClients(fake.NewSimpleClientset(pod), metricsClient, nil),
+	}
+}
+
+func TestCheckResources(t *testing.T) {
+	usage := map[string]corev1.ResourceList{
+		"app":     resourceList("900m", "200Mi"),
+		"sidecar": resourceList("50m", "32Mi"),
+	}
+
+	tests := []struct {
+		name      string
+		limit     int64
+		resources model.Resources
+		failures  []string
+	}{
+		{name: "no thresholds", resources: model.Resources{}},
+		{name: "cpu max", resources: model.Resources{Cpu: model.ResourceThreshold{Max: "500m"}},
+			failures: []string{"pod api-1 container app cpu usage 900m higher than 500m"}},
+		{name: "cpu percent of limits", resources: model.Resources{Cpu: model.ResourceThreshold{MaxPercent: 80}},
+			failures: []string{"container app cpu usage 90.0% of limits 1 higher than 80.0%"}},
+		{name: "memory percent of requests", resources: model.Resources{Memory: model.ResourceThreshold{MaxPercent: 150, Of: "requests"}},
+			failures: []string{"container app memory usage 156.2% of requests 128Mi higher than 150.0%"}},
+		{name: "memory percent below", resources: model.Resources{Memory: model.ResourceThreshold{MaxPercent: 80}}},
+		{name: "containers filter", resources: model.Resources{Containers: []string{"sidecar"}, Cpu: model.ResourceThreshold{Max: "100m"}}},
+		{name: "job limit fallback", limit: 64 << 20,
+			failures: []string{"container app memory usage 200Mi higher than 64Mi"}},
+		{name: "job limit ignored with percent", limit: 64 << 20, resources: model.Resources{Memory: model.ResourceThreshold{MaxPercent: 90}}},
+		{name: "memory max overrides job limit", limit: 64 << 20, resources: model.Resources{Memory: model.ResourceThreshold{Max: "1Gi"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hc := resourcesHealthCheck(usage)
+			err := hc.checkResources(&model.Job{Id: "r", Label: "api", Namespace: "ns", Limit: tt.limit, Resources: tt.resources})
+			if len(tt.failures) == 0 {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || isUnknown(err) {
+				t.Fatalf("expected failures %q, got %v", tt.failures, err)
+			}
+			for _, failure := range tt.failures {
+				if !strings.Contains(err.Error(), failure) {
+					t.Errorf("expected %q, got %v", failure, err)
+				}
+			}
+		})
+	}
+}
+
+func TestCheckResourcesUnknown(t *testing.T) {
+	hc := resourcesHealthCheck(nil)
+	if err := hc.checkResources(&model.Job{Id: "r", Label: "api", Namespace: "ns"}); !isUnknown(err) || !strings.Contains(err.Error(), "no metrics") {
+		t.Errorf("expected unknown without metrics, got %v", err)
+	}
+
+	hc = resourcesHealthCheck(map[string]corev1.ResourceList{"app": resourceList("1", "1Gi")})
+	err := hc.checkResources(&model.Job{Id: "r", Label: "api", Namespace: "ns", Resources: model.Resources{Cpu: model.ResourceThreshold{Max: "half"}}})
+	if !isUnknown(err) {
+		t.Errorf("expected unknown on invalid threshold, got %v", err)
+	}
+
+	if err := (&HealthCheck{}).checkResources(&model.Job{Id: "r"}); !isUnknown(err) {
+		t.Errorf("expected unknown without cluster, got %v", err)
+	}
+}
+
+func TestResourceMax(t *testing.T) {
+	tests := []struct {
+		name      string
+		threshold model.ResourceThreshold
+		expected  int64
+		failure   string
+	}{
+		{name: "cpu", threshold: model.ResourceThreshold{}, expected: -1},
+		{name: "cpu", threshold: model.ResourceThreshold{Max: "1.5"}, expected: 1500},
+		{name: "cpu", threshold: model.ResourceThreshold{Max: "250m"}, expected: 250},
+		{name: "memory", threshold: model.ResourceThreshold{Max: "512Mi"}, expected: 512 << 20},
+		{name: "memory", threshold: model.ResourceThreshold{Max: "1G"}, expected: 1000000000},
+		{name: "memory", threshold: model.ResourceThreshold{Max: "lots"}, failure: "invalid memory max lots"},
+		{name: "cpu", threshold: model.ResourceThreshold{MaxPercent: 80, Of: "requests"}, expected: -1},
+		{name: "cpu", threshold: model.ResourceThreshold{MaxPercent: 80, Of: "capacity"}, failure: "unsupported cpu threshold of capacity"},
+	}
+	for _, tt := range tests {
+		max, err := resourceMax(tt.name, tt.threshold)
+		if tt.failure != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.failure) {
+				t.Errorf("%s %+v: expected %q, got %v", tt.name, tt.threshold, tt.failure, err)
+			}
+			continue
+		}
+		if err != nil || max != tt.expected {
+			t.Errorf("%s %+v: expected %d, got %d %v", tt.name, tt.threshold, tt.expected, max, err)
+		}
+	}
+}
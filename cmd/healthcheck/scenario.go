@@ -0,0 +1,250 @@
+package healthcheck
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/PaesslerAG/jsonpath"
+	"github.com/gorilla/websocket"
+	"github.com/healthcheck-watchdog/cmd/common"
+	"github.com/healthcheck-watchdog/cmd/model"
+	log "github.com/sirupsen/logrus"
+)
+
+// checkScenario runs http and websocket steps in order. Steps share cookies
+// and variables extracted from previous responses. The first failed step fails the job
+func (hc *HealthCheck) checkScenario(function *model.Job) error {
+	steps := function.Scenario.Steps
+	if len(steps) == 0 {
+		return unknown(errors.New("missing scenario steps"))
+	}
+
+	// transport and default redirect policy are configured by job request block
+	client, err := hc.httpClient.getClient(function)
+	if err != nil {
+		return err
+	}
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		return unknown(err)
+	}
+	scenarioClient := *client
+	scenarioClient.Jar = jar
+
+	vars := make(map[string]string, len(function.Scenario.Variables))
+	for key, value := range function.Scenario.Variables {
+		vars[key] = expand(value, nil)
+	}
+
+	start := time.Now()
+	for i := range steps {
+		step := &steps[i]
+		name := stepName(step, i)
+
+		stepStart := time.Now()
+		switch step.Type {
+		case "", common.StepHttp:
+			err = hc.runHttpStep(function, step, &scenarioClient, vars)
+		case common.StepWebsocket:
+			err = hc.runWsStep(function, step, &scenarioClient, vars)
+		default:
+			err = unknown(fmt.Errorf("unsupported step type %s", step.Type))
+		}
+		latency := float64(time.Since(stepStart).Milliseconds())
+		hc.exporter.SetStep(function.Id, name, &latency, err == nil)
+
+		if err != nil {
+			for j := i + 1; j < len(steps); j++ {
+				hc.exporter.SetStep(function.Id, stepName(&steps[j], j), nil, false)
+			}
+			return &StepError{Step: name, Err: err}
+		}
+		log.Debug(fmt.Sprintf("%s: step %s %s", function.Id, name, time.Since(stepStart)))
+	}
+
+	hc.exporter.SetGauge(function.Id, float64(time.Since(start).Milliseconds()))
+	log.Info(fmt.Sprintf("%s %s", function.Id, time.Since(start)))
+
+	return nil
+}
+
+func stepName(step *model.ScenarioStep, i int) string {
+	if step.Name != "" {
+		return step.Name
+	}
+
+	return fmt.Sprintf("%d", i+1)
+}
+
+func (hc *HealthCheck) runHttpStep(function *model.Job, step *model.ScenarioStep, client *http.Client, vars map[string]string) error {
+	// step redirect policy overrides the job one, cookie jar is shared
+	if step.Request.FollowRedirects != nil || step.Request.MaxRedirects > 0 {
+		stepClient := *client
+		stepClient.CheckRedirect = redirectPolicy(&step.Request)
+		client = &stepClient
+	}
+
+	req, err := newHttpRequest(function, &step.Request, step.Url, vars)
+	if err != nil {
+		return unknown(err)
+	}
+	if err := hc.authorize(function, req); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), checkTimeout(function))
+	defer cancel()
+	req = req.WithContext(ctx)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("http %s request on url %s: %s", strings.ToLower(req.Method), req.URL.Redacted(), err.Error())
+	}
+	defer cleanup(resp)
+
+	body, err := checkResponse(step.Assertions, req.URL.Redacted(), resp)
+	if err != nil {
+		return err
+	}
+
+	return extract(step.Extract, resp.Header, body, vars)
+}
+
+// runWsStep connects to websocket, sends request body and waits for the first
+// message satisfying step assertions. Status and header assertions apply to handshake response
+func (hc *HealthCheck) runWsStep(function *model.Job, step *model.ScenarioStep, client *http.Client, vars map[string]string) error {
+	u := expand(step.Url, vars)
+	if len(step.Request.Query) > 0 {
+		parsed, err := url.Parse(u)
+		if err != nil {
+			return unknown(fmt.Errorf("invalid websocket url %s: %w", u, err))
+		}
+		query := parsed.Query()
+		for key, value := range step.Request.Query {
+			query.Set(key, expand(value, vars))
+		}
+		parsed.RawQuery = query.Encode()
+		u = parsed.String()
+	}
+
+	// body file is sent as the first message like body
+	message := step.Request.Body
+	if step.Request.BodyFile != "" {
+		data, err := os.ReadFile(step.Request.BodyFile)
+		if err != nil {
+			return unknown(fmt.Errorf("read body file: %w", err))
+		}
+		message = string(data)
+	}
+
+	header := http.Header{}
+	for key, value := range step.Request.Headers {
+		header.Set(key, expand(value, vars))
+	}
+	if function.AuthEnabled {
+		token, err := hc.authClient.GetToken()
+		if err != nil {
+			return unknown(fmt.Errorf("access token: %w", err))
+		}
+		header.Set("Authorization", "Bearer "+token.AccessToken)
+	}
+
+	timeout := checkTimeout(function)
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	dialer := &websocket.Dialer{
+		Jar:              client.Jar,
+		HandshakeTimeout: timeout,
+	}
+	if transport, ok := client.Transport.(*http.Transport); ok {
+		dialer.TLSClientConfig = transport.TLSClientConfig
+		dialer.Proxy = transport.Proxy
+	}
+
+	conn, resp, err := dialer.DialContext(ctx, u, header)
+	if err != nil {
+		return fmt.Errorf("websocket connect to %s: %s", u, err.Error())
+	}
+	defer conn.Close()
+
+	if message != "" {
+		if err := conn.WriteMessage(websocket.TextMessage, []byte(expand(message, vars))); err != nil {
+			return fmt.Errorf("websocket write on %s: %s", u, err.Error())
+		}
+	}
+
+	if err := conn.SetReadDeadline(time.Now().Add(timeout)); err != nil {
+		return err
+	}
+
+	var failures []string
+	for {
+		_, message, err := conn.ReadMessage()
+		if err != nil {
+			if len(failures) > 0 {
+				return &AssertionError{Url: u, Failures: failures}
+			}
+			return fmt.Errorf("websocket read on %s: %s", u, err.Error())
+		}
+
+		failures = assertResponse(step.Assertions, resp.StatusCode, resp.Header, message)
+		if len(failures) == 0 {
+			return extract(step.Extract, resp.Header, message, vars)
+		}
+	}
+}
+
+// extract stores response header, body JSONPath value or regex group into variables
+func extract(extracts []model.Extract, header http.Header, body []byte, vars map[string]string) error {
+	for _, e := range extracts {
+		var value string
+		switch e.Type {
+		case common.ExtractHeader:
+			values, found := header[http.CanonicalHeaderKey(e.Name)]
+			if !found {
+				return fmt.Errorf("extract %s: missing header %s", e.Variable, e.Name)
+			}
+			value = values[0]
+		case common.ExtractJsonPath:
+			var data interface{}
+			if err := json.Unmarshal(body, &data); err != nil {
+				return fmt.Errorf("extract %s: invalid json body: %s", e.Variable, err.Error())
+			}
+			result, err := jsonpath.Get(e.Path, data)
+			if err != nil {
+				return fmt.Errorf("extract %s: jsonpath %s: %s", e.Variable, e.Path, err.Error())
+			}
+			value = jsonString(result)
+		case common.ExtractRegex:
+			re, err := regexp.Compile(e.Pattern)
+			if err != nil {
+				return unknown(fmt.Errorf("extract %s: invalid regex %s", e.Variable, e.Pattern))
+			}
+			// first capturing group or the whole match
+			match := re.FindSubmatch(body)
+			if match == nil {
+				return fmt.Errorf("extract %s: regex %s matched nothing", e.Variable, e.Pattern)
+			}
+			value = string(match[0])
+			if len(match) > 1 {
+				value = string(match[1])
+			}
+		default:
+			return unknown(fmt.Errorf("unsupported extract type %s", e.Type))
+		}
+
+		vars[e.Variable] = value
+	}
+
+	return nil
+}
@@ -0,0 +1,172 @@
+package healthcheck
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/websocket"
+	"github.com/healthcheck-watchdog/cmd/exporter"
+	"github.com/healthcheck-watchdog/cmd/model"
+)
+
+func TestScenarioStepRedirects(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/login" {
+			http.SetCookie(w, &http.Cookie{Name: "session", Value: "s1"})
+			http.Redirect(w, r, "/home", http.StatusFound)
+			return
+		}
+		if cookie, err := r.Cookie("session"); err != nil || cookie.Value != "s1" {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer server.Close()
+
+	follow := false
+	login := model.ScenarioStep{Name: "login", Url: server.URL + "/login", Request: model.Request{FollowRedirects: &follow},
+		Assertions: []model.Assertion{{Type: "status", Codes: []string{"302"}}}}
+	home := model.ScenarioStep{Name: "home", Url: server.URL + "/home"}
+
+	tests := []struct {
+		name    string
+		steps   []model.ScenarioStep
+		failure string
+	}{
+		// login step keeps redirect response, home step is sent with the session cookie of login step
+		{name: "cookie", steps: []model.ScenarioStep{login, home}},
+		{name: "without cookie", steps: []model.ScenarioStep{home}, failure: "step home: invalid response code 401"},
+	}
+	for _, tt := range tests {
+		function := &model.Job{Id: "s", Type: "scenario", Scenario: model.Scenario{Steps: tt.steps}}
+		hc := &HealthCheck{exporter: &exporter.Exporter{}, httpClient: NewHttpClient()}
+		err := hc.checkScenario(function)
+		if tt.failure == "" {
+			if err != nil {
+				t.Errorf("%s: unexpected error: %v", tt.name, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.failure) {
+			t.Errorf("%s: expected %q, got %v", tt.name, tt.failure, err)
+		}
+	}
+}
+
+func TestScenarioVariables(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/token":
+			body, _ := io.ReadAll(r.Body)
+			if string(body) != `{"user":"u1"}` {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			w.Header().Set("X-Request-Id", "r1")
+			fmt.Fprint(w, `{"access":{"token":"t1","ttl":300},"form":"<input name=csrf value=c1>"}`)
+		case "/items/r1":
+			if r.Header.Get("Authorization") != "Bearer t1" || r.URL.Query().Get("csrf") != "c1" || r.URL.Query().Get("ttl") != "300" {
+				w.WriteHeader(http.StatusForbidden)
+			}
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	token := model.ScenarioStep{Name: "token", Url: server.URL + "/token", Request: model.Request{Body: `{"user":"${user}"}`},
+		Extract: []model.Extract{
+			{Variable: "request", Type: "header", Name: "x-request-id"},
+			{Variable: "token", Type: "jsonpath", Path: "$.access.token"},
+			{Variable: "ttl", Type: "jsonpath", Path: "$.access.ttl"},
+			{Variable: "csrf", Type: "regex", Pattern: `csrf value=(\w+)`},
+		}}
+	items := model.ScenarioStep{Name: "items", Url: server.URL + "/items/${request}",
+		Request: model.Request{
+			Headers: map[string]string{"Authorization": "Bearer ${token}"},
+			Query:   map[string]string{"csrf": "${csrf}", "ttl": "${ttl}"},
+		}}
+
+	tests := []struct {
+		name    string
+		extract *model.Extract
+		failure string
+	}{
+		{name: "extracted"},
+		{name: "missing header", extract: &model.Extract{Variable: "id", Type: "header", Name: "X-Trace-Id"}, failure: "extract id: missing header X-Trace-Id"},
+		{name: "missing jsonpath", extract: &model.Extract{Variable: "id", Type: "jsonpath", Path: "$.id"}, failure: "extract id: jsonpath $.id"},
+		{name: "regex matched nothing", extract: &model.Extract{Variable: "id", Type: "regex", Pattern: `id=(\d+)`}, failure: `extract id: regex id=(\d+) matched nothing`},
+	}
+	for _, tt := range tests {
+		step := token
+		if tt.extract != nil {
+			step.Extract = append(append([]model.Extract(nil), token.Extract...), *tt.extract)
+		}
+		function := &model.Job{Id: "s", Type: "scenario", Scenario: model.Scenario{
+			Variables: map[string]string{"user": "u1"},
+			Steps:     []model.ScenarioStep{step, items},
+		}}
+		hc := &HealthCheck{exporter: &exporter.Exporter{}, httpClient: NewHttpClient()}
+		err := hc.checkScenario(function)
+		if tt.failure == "" {
+			if err != nil {
+				t.Errorf("%s: unexpected error: %v", tt.name, err)
+			}
+			continue
+		}
+		var stepErr *StepError
+		if !errors.As(err, &stepErr) || stepErr.Step != "token" || !strings.Contains(err.Error(), tt.failure) {
+			t.Errorf("%s: expected %q, got %v", tt.name, tt.failure, err)
+		}
+	}
+}
+
+func TestScenarioWebsocketStep(t *testing.T) {
+	// server echoes the first message with token query parameter of the upgrade request
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := r.URL.Query().Get("token")
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		_, message, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		conn.WriteMessage(websocket.TextMessage, []byte(fmt.Sprintf(`{"token":%q,"message":%s}`, token, message))) //nolint:errcheck // test server
+	}))
+	defer server.Close()
+
+	bodyFile := filepath.Join(t.TempDir(), "subscribe.json")
+	if err := os.WriteFile(bodyFile, []byte(`{"user":"${user}"}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	step := model.ScenarioStep{Name: "subscribe", Type: "websocket", Url: "ws" + strings.TrimPrefix(server.URL, "http") + "/stream",
+		Request: model.Request{Query: map[string]string{"token": "${user}-token"}, BodyFile: bodyFile},
+		Assertions: []model.Assertion{
+			{Type: "jsonpath", Path: "$.token", Value: "u1-token"},
+			{Type: "jsonpath", Path: "$.message.user", Value: "u1"},
+		}}
+	function := &model.Job{Id: "s", Type: "scenario", Scenario: model.Scenario{
+		Variables: map[string]string{"user": "u1"},
+		Steps:     []model.ScenarioStep{step},
+	}}
+	hc := &HealthCheck{exporter: &exporter.Exporter{}, httpClient: NewHttpClient()}
+	if err := hc.checkScenario(function); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	function.Scenario.Steps[0].Request.BodyFile = filepath.Join(t.TempDir(), "missing.json")
+	if err := hc.checkScenario(function); !isUnknown(err) || !strings.Contains(err.Error(), "read body file") {
+		t.Errorf("expected unknown for missing body file, got %v", err)
+	}
+}
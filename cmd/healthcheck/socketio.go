@@ -0,0 +1,216 @@
+package healthcheck
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/healthcheck-watchdog/cmd/common"
+	"github.com/healthcheck-watchdog/cmd/model"
+)
+
+// engine.io and socket.io packet types
+const (
+	engineOpen    = '0'
+	engineClose   = '1'
+	enginePing    = '2'
+	engineMessage = '4'
+
+	socketConnect      = '0'
+	socketDisconnect   = '1'
+	socketEvent        = '2'
+	socketConnectError = '4'
+)
+
+// socketIoProtocol speaks Socket.IO v4 over engine.io websocket transport.
+// It connects to the namespace after engine.io open packet, emits subscribe
+// event and passes arguments of events
+type socketIoProtocol struct {
+	function  *model.Job
+	token     string
+	namespace string
+}
+
+func newSocketIoProtocol(function *model.Job, token string) *socketIoProtocol {
+	namespace := function.Websocket.SocketIo.Namespace
+	if namespace == "" {
+		namespace = common.DefaultSocketIoNamespace
+	}
+	if !strings.HasPrefix(namespace, "/") {
+		namespace = "/" + namespace
+	}
+
+	return &socketIoProtocol{function: function, token: token, namespace: namespace}
+}
+
+// url adds engine.io path and query parameters of websocket transport
+func (p *socketIoProtocol) url(u string) (string, error) {
+	parsed, err := url.Parse(u)
+	if err != nil {
+		return "", err
+	}
+	if parsed.Path == "" || parsed.Path == "/" {
+		parsed.Path = "/socket.io/"
+	}
+	query := parsed.Query()
+	query.Set("EIO", "4")
+	query.Set("transport", "websocket")
+	parsed.RawQuery = query.Encode()
+
+	return parsed.String(), nil
+}
+
+func (p *socketIoProtocol) open() [][]byte {
+	return nil
+}
+
+func (p *socketIoProtocol) decode(frame []byte) ([][]byte, [][]byte, error) {
+	if len(frame) == 0 {
+		return nil, nil, nil
+	}
+
+	switch frame[0] {
+	case engineOpen:
+		return nil, [][]byte{p.connect()}, nil
+	case enginePing:
+		return nil, [][]byte{append([]byte{'3'}, frame[1:]...)}, nil
+	case engineClose:
+		return nil, nil, errors.New("socket.io: engine closed")
+	case engineMessage:
+		return p.decodePacket(frame[1:])
+	}
+
+	return nil, nil, nil
+}
+
+// decodePacket handles socket.io packet of the job namespace
+func (p *socketIoProtocol) decodePacket(packet []byte) ([][]byte, [][]byte, error) {
+	if len(packet) == 0 {
+		return nil, nil, nil
+	}
+	kind, data := packet[0], string(packet[1:])
+
+	namespace := common.DefaultSocketIoNamespace
+	if strings.HasPrefix(data, "/") {
+		namespace, data, _ = strings.Cut(data, ",")
+	}
+	if namespace != p.namespace {
+		return nil, nil, nil
+	}
+
+	switch kind {
+	case socketConnect:
+		if subscribe := p.subscribe(); subscribe != nil {
+			return nil, [][]byte{subscribe}, nil
+		}
+	case socketDisconnect:
+		return nil, nil, fmt.Errorf("socket.io: disconnected from namespace %s", p.namespace)
+	case socketConnectError:
+		return nil, nil, fmt.Errorf("socket.io: connect to namespace %s: %s", p.namespace, truncate(data))
+	case socketEvent:
+		// skip acknowledgement id
+		data = strings.TrimLeft(data, "0123456789")
+
+		var args []json.RawMessage
+		if err := json.Unmarshal([]byte(data), &args); err != nil || len(args) == 0 {
+			return nil, nil, fmt.Errorf("socket.io: invalid event %s", truncate(data))
+		}
+		var event string
+		if err := json.Unmarshal(args[0], &event); err != nil {
+			return nil, nil, fmt.Errorf("socket.io: invalid event name %s", args[0])
+		}
+		if events := p.function.Websocket.SocketIo.Events; len(events) > 0 && !contains(events, event) {
+			return nil, nil, nil
+		}
+
+		// single argument is passed as is, several as json array
+		args = args[1:]
+		switch len(args) {
+		case 0:
+			return [][]byte{[]byte("null")}, nil, nil
+		case 1:
+			return [][]byte{args[0]}, nil, nil
+		}
+		payload, err := json.Marshal(args)
+		if err != nil {
+			return nil, nil, err
+		}
+		return [][]byte{payload}, nil, nil
+	}
+
+	return nil, nil, nil
+}
+
+// heartbeat is not needed, server pings and client answers
+func (p *socketIoProtocol) heartbeat() []byte {
+	return nil
+}
+
+// encode emits message as requestEvent
+func (p *socketIoProtocol) encode(message []byte) []byte {
+	event := p.function.Websocket.SocketIo.RequestEvent
+	if event == "" {
+		event = common.DefaultSocketIoRequestEvent
+	}
+
+	return p.emit(event, message)
+}
+
+// connect joins the namespace with access token in auth payload
+func (p *socketIoProtocol) connect() []byte {
+	packet := []byte{engineMessage, socketConnect}
+	packet = append(packet, p.prefix()...)
+
+	if wsMessageAuth(p.function) {
+		template := p.function.Websocket.AuthTemplate
+		if template == "" {
+			template = common.DefaultSocketIoAuth
+		}
+		packet = append(packet, expand(template, map[string]string{"token": p.token})...)
+	}
+
+	return packet
+}
+
+// subscribe emits subscribe payload, nil without it
+func (p *socketIoProtocol) subscribe() []byte {
+	config := &p.function.Websocket
+	if config.Subscribe == "" {
+		return nil
+	}
+	event := config.SocketIo.SubscribeEvent
+	if event == "" {
+		event = common.DefaultSocketIoSubscribeEvent
+	}
+
+	return p.emit(event, []byte(expand(config.Subscribe, map[string]string{"token": p.token})))
+}
+
+// emit builds event packet. Payload which is not json is sent as string
+func (p *socketIoProtocol) emit(event string, payload []byte) []byte {
+	if !json.Valid(payload) {
+		payload, _ = json.Marshal(string(payload))
+	}
+	name, _ := json.Marshal(event)
+
+	packet := []byte{engineMessage, socketEvent}
+	packet = append(packet, p.prefix()...)
+	packet = append(packet, '[')
+	packet = append(packet, name...)
+	packet = append(packet, ',')
+	packet = append(packet, payload...)
+	packet = append(packet, ']')
+
+	return packet
+}
+
+// prefix is namespace prefix of packets outside the main namespace
+func (p *socketIoProtocol) prefix() string {
+	if p.namespace == common.DefaultSocketIoNamespace {
+		return ""
+	}
+
+	return p.namespace + ","
+}
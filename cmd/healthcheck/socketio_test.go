@@ -0,0 +1,102 @@
+package healthcheck
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/healthcheck-watchdog/cmd/model"
+)
+
+func TestSocketIoUrl(t *testing.T) {
+	protocol := newSocketIoProtocol(&model.Job{}, "")
+	tests := map[string]string{
+		"wss://api.test":            "wss://api.test/socket.io/?EIO=4&transport=websocket",
+		"wss://api.test/":           "wss://api.test/socket.io/?EIO=4&transport=websocket",
+		"ws://api.test/rt/?room=a1": "ws://api.test/rt/?EIO=4&room=a1&transport=websocket",
+	}
+	for u, expected := range tests {
+		if result, err := protocol.url(u); err != nil || result != expected {
+			t.Errorf("%s: expected %s, got %s %v", u, expected, result, err)
+		}
+	}
+}
+
+func TestSocketIoDecode(t *testing.T) {
+	function := &model.Job{AuthEnabled: true, Websocket: model.Websocket{
+		Subscribe: `{"room":"ticks"}`,
+		SocketIo:  model.SocketIo{Namespace: "chat", Events: []string{"tick", "pair"}},
+	}}
+	protocol := newSocketIoProtocol(function, "secret")
+
+	tests := []struct {
+		name     string
+		frame    string
+		payloads []string
+		replies  []string
+		failure  string
+	}{
+		{name: "open", frame: `0{"sid":"a","pingInterval":25000}`, replies: []string{`40/chat,{"token":"secret"}`}},
+		{name: "ping", frame: "2probe", replies: []string{"3probe"}},
+		{name: "connect", frame: `40/chat,{"sid":"b"}`, replies: []string{`42/chat,["subscribe",{"room":"ticks"}]`}},
+		{name: "connect other namespace", frame: `40{"sid":"c"}`},
+		{name: "event", frame: `42/chat,["tick",{"v":1}]`, payloads: []string{`{"v":1}`}},
+		{name: "event with ack id", frame: `42/chat,17["tick",{"v":2}]`, payloads: []string{`{"v":2}`}},
+		{name: "event without args", frame: `42/chat,["tick"]`, payloads: []string{"null"}},
+		{name: "event with args", frame: `42/chat,["pair",1,"a"]`, payloads: []string{`[1,"a"]`}},
+		{name: "filtered event", frame: `42/chat,["news",{"v":3}]`},
+		{name: "main namespace event", frame: `42["tick",{"v":4}]`},
+		{name: "invalid event", frame: `42/chat,{"v":5}`, failure: "invalid event"},
+		{name: "invalid event name", frame: `42/chat,[5]`, failure: "invalid event name"},
+		{name: "connect error", frame: `44/chat,{"message":"unauthorized"}`, failure: "connect to namespace /chat"},
+		{name: "disconnect", frame: "41/chat,", failure: "disconnected from namespace /chat"},
+		{name: "engine close", frame: "1", failure: "engine closed"},
+		{name: "noop", frame: "6"},
+	}
+	for _, tt := range tests {
+		payloads, replies, err := protocol.decode([]byte(tt.frame))
+		if tt.failure != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.failure) {
+				t.Errorf("%s: expected %q, got %v", tt.name, tt.failure, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.name, err)
+			continue
+		}
+		if !equalFrames(payloads, tt.payloads) {
+			t.Errorf("%s: expected payloads %q, got %q", tt.name, tt.payloads, payloads)
+		}
+		if !equalFrames(replies, tt.replies) {
+			t.Errorf("%s: expected replies %q, got %q", tt.name, tt.replies, replies)
+		}
+	}
+}
+
+func TestSocketIoEncode(t *testing.T) {
+	protocol := newSocketIoProtocol(&model.Job{}, "")
+	if packet := string(protocol.encode([]byte(`{"id":"r1"}`))); packet != `42["message",{"id":"r1"}]` {
+		t.Errorf("unexpected json event %s", packet)
+	}
+	if packet := string(protocol.encode([]byte("ping me"))); packet != `42["message","ping me"]` {
+		t.Errorf("expected text payload sent as string, got %s", packet)
+	}
+
+	// access token is sent only with auth_enabled
+	if packet := string(protocol.connect()); packet != "40" {
+		t.Errorf("expected connect without auth, got %s", packet)
+	}
+}
+
+func equalFrames(frames [][]byte, expected []string) bool {
+	if len(frames) != len(expected) {
+		return false
+	}
+	for i := range frames {
+		if string(frames[i]) != expected[i] {
+			return false
+		}
+	}
+
+	return true
+}
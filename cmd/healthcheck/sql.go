@@ -0,0 +1,169 @@
+package healthcheck
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/healthcheck-watchdog/cmd/model"
+	log "github.com/sirupsen/logrus"
+)
+
+// SqlClient keeps connection pools of sql jobs
+type SqlClient struct {
+	mx  sync.Mutex
+	dbs map[string]*sql.DB
+}
+
+func NewSqlClient() *SqlClient {
+	return &SqlClient{
+		dbs: make(map[string]*sql.DB),
+	}
+}
+
+func (sc *SqlClient) getDb(function *model.Job) (*sql.DB, error) {
+	sc.mx.Lock()
+	defer sc.mx.Unlock()
+
+	if db, found := sc.dbs[function.Id]; found {
+		return db, nil
+	}
+
+	dsn, err := sqlConnectionString(&function.Sql)
+	if err != nil {
+		return nil, unknown(err)
+	}
+
+	db, err := sql.Open(function.Sql.Driver, dsn)
+	if err != nil {
+		// unknown driver or malformed connection string
+		return nil, unknown(fmt.Errorf("sql open: %w", err))
+	}
+	db.SetMaxOpenConns(1)
+	sc.dbs[function.Id] = db
+
+	return db, nil
+}
+
+// sqlConnectionString resolves connection string from configuration,
+// environment variable or secret file
+func sqlConnectionString(config *model.Sql) (string, error) {
+	switch {
+	case config.ConnectionStringEnv != "":
+		dsn, found := os.LookupEnv(config.ConnectionStringEnv)
+		if !found {
+			return "", fmt.Errorf("missing environment variable %s", config.ConnectionStringEnv)
+		}
+		return dsn, nil
+	case config.ConnectionStringFile != "":
+		data, err := os.ReadFile(config.ConnectionStringFile)
+		if err != nil {
+			return "", fmt.Errorf("read connection string: %w", err)
+		}
+		return strings.TrimSpace(string(data)), nil
+	case config.ConnectionString != "":
+		return config.ConnectionString, nil
+	}
+
+	return "", errors.New("missing sql connection string")
+}
+
+// checkSql runs configured query and asserts on row count and scalar value
+func (hc *HealthCheck) checkSql(function *model.Job) error {
+	db, err := hc.sqlClient.getDb(function)
+	if err != nil {
+		return err
+	}
+
+	timeout := checkTimeout(function)
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	start := time.Now()
+	rows, err := db.QueryContext(ctx, function.Sql.Query)
+	if err != nil {
+		return fmt.Errorf("sql query: %s", err.Error())
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return fmt.Errorf("sql query: %s", err.Error())
+	}
+
+	count := 0
+	var scalar interface{}
+	for rows.Next() {
+		if count == 0 && len(columns) > 0 {
+			values := make([]interface{}, len(columns))
+			for i := range values {
+				values[i] = new(interface{})
+			}
+			if err := rows.Scan(values...); err != nil {
+				return fmt.Errorf("sql scan: %s", err.Error())
+			}
+			scalar = *(values[0].(*interface{}))
+		}
+		count++
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("sql query: %s", err.Error())
+	}
+
+	hc.exporter.SetGauge(function.Id, float64(time.Since(start).Milliseconds()))
+	log.Info(fmt.Sprintf("%s %s", function.Id, time.Since(start)))
+
+	if function.Sql.MinRows != nil && count < *function.Sql.MinRows {
+		return fmt.Errorf("sql query returned %d rows, expected at least %d", count, *function.Sql.MinRows)
+	}
+	if function.Sql.MaxRows != nil && count > *function.Sql.MaxRows {
+		return fmt.Errorf("sql query returned %d rows, expected at most %d", count, *function.Sql.MaxRows)
+	}
+
+	if function.Sql.Equals == "" && function.Sql.Min == nil && function.Sql.Max == nil {
+		return nil
+	}
+	if count == 0 {
+		return errors.New("sql query returned no value")
+	}
+
+	value := sqlString(scalar)
+	if function.Sql.Equals != "" && value != function.Sql.Equals {
+		return fmt.Errorf("sql value is %s, expected %s", value, function.Sql.Equals)
+	}
+	if function.Sql.Min == nil && function.Sql.Max == nil {
+		return nil
+	}
+
+	number, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return fmt.Errorf("sql value is not a number: %s", value)
+	}
+	if function.Sql.Min != nil && number < *function.Sql.Min {
+		return fmt.Errorf("sql value is %s, expected at least %g", value, *function.Sql.Min)
+	}
+	if function.Sql.Max != nil && number > *function.Sql.Max {
+		return fmt.Errorf("sql value is %s, expected at most %g", value, *function.Sql.Max)
+	}
+
+	return nil
+}
+
+func sqlString(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case []byte:
+		return string(v)
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	}
+
+	return fmt.Sprint(value)
+}
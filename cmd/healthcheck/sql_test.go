@@ -0,0 +1,94 @@
+package healthcheck
+
+import (
+	"database/sql"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/healthcheck-watchdog/cmd/exporter"
+	"github.com/healthcheck-watchdog/cmd/model"
+)
+
+// sqliteDb creates database with queue table of three rows
+func sqliteDb(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "test.db")
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	for _, statement := range []string{
+		"CREATE TABLE queue (id INTEGER PRIMARY KEY, state TEXT)",
+		"INSERT INTO queue (state) VALUES ('new'), ('new'), ('done')",
+	} {
+		if _, err := db.Exec(statement); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return path
+}
+
+func TestCheckSql(t *testing.T) {
+	path := sqliteDb(t)
+	one, two, five := 1, 2, 5
+	low, high := 1.0, 2.5
+
+	tests := []struct {
+		name    string
+		sql     model.Sql
+		failure string
+	}{
+		{name: "min rows", sql: model.Sql{Query: "SELECT id FROM queue", MinRows: &two}},
+		{name: "too few rows", sql: model.Sql{Query: "SELECT id FROM queue WHERE state = 'done'", MinRows: &two}, failure: "returned 1 rows, expected at least 2"},
+		{name: "too many rows", sql: model.Sql{Query: "SELECT id FROM queue", MaxRows: &one}, failure: "returned 3 rows, expected at most 1"},
+		{name: "scalar range", sql: model.Sql{Query: "SELECT count(*) FROM queue WHERE state = 'new'", Min: &low, Max: &high}},
+		{name: "scalar above max", sql: model.Sql{Query: "SELECT count(*) FROM queue", Max: &high}, failure: "sql value is 3, expected at most 2.5"},
+		{name: "equals", sql: model.Sql{Query: "SELECT state FROM queue ORDER BY id DESC", Equals: "done"}},
+		{name: "not equals", sql: model.Sql{Query: "SELECT state FROM queue ORDER BY id", Equals: "done"}, failure: "sql value is new, expected done"},
+		{name: "not a number", sql: model.Sql{Query: "SELECT state FROM queue", Min: &low}, failure: "sql value is not a number: new"},
+		{name: "no value", sql: model.Sql{Query: "SELECT id FROM queue WHERE id > 5", MaxRows: &five, Equals: "1"}, failure: "sql query returned no value"},
+		{name: "query error", sql: model.Sql{Query: "SELECT * FROM missing"}, failure: "no such table"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hc := &HealthCheck{exporter: &exporter.Exporter{}, sqlClient: NewSqlClient()}
+			tt.sql.Driver = "sqlite"
+			tt.sql.ConnectionString = path
+			err := hc.checkSql(&model.Job{Id: "s", Sql: tt.sql})
+			if tt.failure == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || isUnknown(err) || !strings.Contains(err.Error(), tt.failure) {
+				t.Errorf("expected %q, got %v", tt.failure, err)
+			}
+		})
+	}
+}
+
+func TestCheckSqlConnectionString(t *testing.T) {
+	path := sqliteDb(t)
+	t.Setenv("TEST_SQL_DSN", path)
+
+	hc := &HealthCheck{exporter: &exporter.Exporter{}, sqlClient: NewSqlClient()}
+	err := hc.checkSql(&model.Job{Id: "env", Sql: model.Sql{Driver: "sqlite", ConnectionStringEnv: "TEST_SQL_DSN", Query: "SELECT 1"}})
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	for _, config := range []model.Sql{
+		{Driver: "sqlite", ConnectionStringEnv: "TEST_SQL_MISSING", Query: "SELECT 1"},
+		{Driver: "sqlite", Query: "SELECT 1"},
+		{Driver: "unknown", ConnectionString: path, Query: "SELECT 1"},
+	} {
+		if err := hc.checkSql(&model.Job{Id: "unknown-" + config.Driver + config.ConnectionStringEnv, Sql: config}); !isUnknown(err) {
+			t.Errorf("expected unknown for %+v, got %v", config, err)
+		}
+	}
+}
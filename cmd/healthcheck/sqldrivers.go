@@ -0,0 +1,10 @@
+package healthcheck
+
+// database/sql drivers available for sql jobs: postgres and sqlite. Both are
+// supported in production, sqlite is pure Go and checks file databases, e.g.
+// on a volume shared with the application. Add blank import of another driver
+// to make it available by its registered name
+import (
+	_ "github.com/lib/pq"
+	_ "modernc.org/sqlite"
+)
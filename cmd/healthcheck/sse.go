@@ -0,0 +1,268 @@
+package healthcheck
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/healthcheck-watchdog/cmd/authentication"
+	"github.com/healthcheck-watchdog/cmd/common"
+	"github.com/healthcheck-watchdog/cmd/exporter"
+	"github.com/healthcheck-watchdog/cmd/model"
+	log "github.com/sirupsen/logrus"
+)
+
+// sseLineLimit is maximal length of event stream line
+const sseLineLimit = 1 << 20
+
+// SseClient manages server-sent events streams of jobs. Each stream is served
+// by its own goroutine and resumed from the last event id after reconnect
+type SseClient struct {
+	mx          sync.Mutex
+	ctx         context.Context
+	cancel      context.CancelFunc
+	connections map[string]*SseConnection
+	prometheus  *exporter.Exporter
+	authClient  *authentication.AuthClient
+	httpClient  *HttpClient
+}
+
+// SseConnection holds state of a single job url stream
+type SseConnection struct {
+	streamState
+	lastEventId string
+}
+
+func NewSseClient(prometheus *exporter.Exporter, authClient *authentication.AuthClient, httpClient *HttpClient) *SseClient {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &SseClient{
+		ctx:         ctx,
+		cancel:      cancel,
+		connections: make(map[string]*SseConnection),
+		prometheus:  prometheus,
+		authClient:  authClient,
+		httpClient:  httpClient,
+	}
+}
+
+// Close stops all streams
+func (sc *SseClient) Close() {
+	sc.cancel()
+}
+
+// getConnection returns stream of the job url, starting it on first call
+func (sc *SseClient) getConnection(function *model.Job, u string) *SseConnection {
+	sc.mx.Lock()
+	defer sc.mx.Unlock()
+
+	key := fmt.Sprintf("%s/%s", function.Id, u)
+	c, found := sc.connections[key]
+	if !found {
+		log.Info(fmt.Sprintf("%s. Registering url: %s", function.Id, u))
+		c = &SseConnection{}
+		c.init(function, u, function.Sse.Interval, function.Sse.Mode == common.ModeThroughput)
+		sc.connections[key] = c
+		go c.reconnect(sc.ctx, sc.prometheus, func() (bool, error) {
+			return sc.serve(c)
+		})
+	}
+
+	return c
+}
+
+// serve opens event stream and reads events until stream fails. Reports whether any event was received
+func (sc *SseClient) serve(c *SseConnection) (bool, error) {
+	function := c.function
+
+	client, err := sc.httpClient.getClient(function)
+	if err != nil {
+		return false, err
+	}
+	req, err := newHttpRequest(function, &function.Request, c.url, nil)
+	if err != nil {
+		return false, err
+	}
+
+	ctx, cancel := context.WithCancel(sc.ctx)
+	defer cancel()
+	req = req.WithContext(ctx)
+
+	req.Header.Set("Accept", "text/event-stream")
+	req.Header.Set("Cache-Control", "no-cache")
+	c.mx.Lock()
+	if c.lastEventId != "" {
+		req.Header.Set("Last-Event-ID", c.lastEventId)
+	}
+	c.mx.Unlock()
+	token, err := wsToken(sc.authClient, function)
+	if err != nil {
+		return false, err
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return false, fmt.Errorf("connect: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("invalid response code %d", resp.StatusCode)
+	}
+	if contentType := resp.Header.Get("Content-Type"); !strings.HasPrefix(contentType, "text/event-stream") {
+		return false, fmt.Errorf("invalid content type %s", contentType)
+	}
+
+	c.setState(sc.prometheus, common.StreamConnected, nil)
+	log.Info(fmt.Sprintf("%s. Connected sse (%s)", function.Id, c.url))
+
+	// close stream without events for ResponseTimeout
+	var staleErr error
+	connected := time.Now()
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		ticker := time.NewTicker(time.Second)
+		defer ticker.Stop()
+		timeout := time.Duration(function.ResponseTimeout) * time.Second
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if timeout > 0 && c.idle(connected) > timeout {
+					c.mx.Lock()
+					staleErr = fmt.Errorf("no events for %s", timeout)
+					c.mx.Unlock()
+					cancel()
+					return
+				}
+			}
+		}
+	}()
+
+	received := false
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), sseLineLimit)
+
+	var event, id string
+	var data []string
+	hasId := false
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		// empty line dispatches the event
+		if line == "" {
+			if hasId {
+				c.mx.Lock()
+				c.lastEventId = id
+				c.mx.Unlock()
+			}
+			if len(data) > 0 {
+				received = true
+				sc.handle(c, event, strings.Join(data, "\n"))
+			}
+			event, data, hasId = "", nil, false
+			continue
+		}
+
+		// comment, usually keepalive
+		if strings.HasPrefix(line, ":") {
+			continue
+		}
+
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "event":
+			event = value
+		case "data":
+			data = append(data, value)
+		case "id":
+			if !strings.Contains(value, "\x00") {
+				id, hasId = value, true
+			}
+		case "retry":
+			// reconnect delay in milliseconds, values with non-digits are ignored
+			if ms, err := strconv.ParseUint(value, 10, 32); err == nil {
+				c.setRetry(time.Duration(ms) * time.Millisecond)
+			}
+		}
+	}
+
+	c.mx.Lock()
+	err = staleErr
+	c.mx.Unlock()
+	if err != nil {
+		return received, err
+	}
+	if err := scanner.Err(); err != nil {
+		return received, fmt.Errorf("read: %w", err)
+	}
+
+	return received, errors.New("stream closed by server")
+}
+
+// handle counts event filtered by name and evaluates data assertions
+func (sc *SseClient) handle(c *SseConnection, event string, data string) {
+	function := c.function
+	if event == "" {
+		event = "message"
+	}
+	log.Debug(fmt.Sprintf("%s. Received event %s: %s", function.Id, event, data))
+
+	if len(function.Sse.Events) > 0 && !contains(function.Sse.Events, event) {
+		return
+	}
+
+	sc.prometheus.IncCounter(function.Id, messageLabel(function.Sse.LabelPath, []byte(data)))
+
+	if failures := assertResponse(function.Sse.Assertions, 0, nil, []byte(data)); len(failures) > 0 {
+		log.Warn(fmt.Sprintf("%s. Event on %s does not match assertions: %s", function.Id, c.url, strings.Join(failures, "; ")))
+		return
+	}
+
+	c.record(sc.prometheus)
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+// checkSse evaluates freshness or rate of events of each url stream
+func (hc *HealthCheck) checkSse(function *model.Job) error {
+	for _, u := range function.Urls {
+		connection := hc.sseClient.getConnection(function, u)
+		hc.exporter.SetMessageAge(function.Id, u, connection.messageAge().Seconds())
+
+		var err error
+		switch function.Sse.Mode {
+		case "", common.ModeStream:
+			err = checkFreshness("sse", &connection.streamState)
+		case common.ModeThroughput:
+			err = hc.checkThroughput("sse", &connection.streamState, function.Sse.MinMessages)
+		default:
+			err = unknown(fmt.Errorf("unsupported sse mode %s", function.Sse.Mode))
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
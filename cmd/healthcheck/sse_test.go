@@ -0,0 +1,60 @@
+package healthcheck
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/healthcheck-watchdog/cmd/authentication"
+	"github.com/healthcheck-watchdog/cmd/common"
+	"github.com/healthcheck-watchdog/cmd/exporter"
+	"github.com/healthcheck-watchdog/cmd/model"
+)
+
+func TestSseRetry(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte("retry: 250\n\nretry: 1s\ndata: tick\nid: 7\n\n")) //nolint:errcheck // test server
+	}))
+	defer server.Close()
+
+	sc := NewSseClient(&exporter.Exporter{}, nil, NewHttpClient())
+	defer sc.Close()
+
+	c := &SseConnection{}
+	c.init(&model.Job{Id: "sse"}, server.URL, 0, false)
+	received, err := sc.serve(c)
+	if !received || err == nil {
+		t.Fatalf("expected event and closed stream, got %v, %v", received, err)
+	}
+	if backoff := c.minBackoff(); backoff != 250*time.Millisecond {
+		t.Errorf("expected reconnect delay 250ms, got %s", backoff)
+	}
+	if c.lastEventId != "7" {
+		t.Errorf("expected last event id 7, got %q", c.lastEventId)
+	}
+}
+
+func TestSseTokenFailureIsUnknown(t *testing.T) {
+	// token endpoint is not listening
+	authClient := authentication.NewAuthClient(&model.Config{Authentication: model.Authentication{
+		AuthUrl: "http://127.0.0.1:1", ClientId: "id", ClientSecret: "secret",
+	}})
+	sc := NewSseClient(&exporter.Exporter{}, authClient, NewHttpClient())
+	defer sc.Close()
+
+	c := &SseConnection{}
+	c.init(&model.Job{Id: "sse-auth", AuthEnabled: true}, "http://127.0.0.1:1/events", 0, false)
+	_, err := sc.serve(c)
+	if !isUnknown(err) {
+		t.Errorf("expected unknown, got %v", err)
+	}
+
+	c.setState(sc.prometheus, common.StreamBackoff, err)
+	c.lastMessage = time.Now().Add(-time.Hour)
+	c.function.Timeout = 60
+	if err := checkFreshness("sse", &c.streamState); !isUnknown(err) {
+		t.Errorf("expected stale stream with token failure to be unknown, got %v", err)
+	}
+}
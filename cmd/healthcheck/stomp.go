@@ -0,0 +1,230 @@
+package healthcheck
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/healthcheck-watchdog/cmd/common"
+	"github.com/healthcheck-watchdog/cmd/model"
+)
+
+var stompEscaper = strings.NewReplacer("\\", "\\\\", ":", "\\c", "\n", "\\n", "\r", "\\r")
+var stompUnescaper = strings.NewReplacer("\\\\", "\\", "\\c", ":", "\\n", "\n", "\\r", "\r")
+
+// stompProtocol connects to STOMP broker, subscribes to destination
+// after CONNECTED frame and passes bodies of MESSAGE frames
+type stompProtocol struct {
+	function *model.Job
+	host     string
+	token    string
+}
+
+// stompFrame is a parsed STOMP frame, only the first occurrence of a header is kept
+type stompFrame struct {
+	command string
+	headers map[string]string
+	body    []byte
+}
+
+func newStompProtocol(function *model.Job, u string, token string) (*stompProtocol, error) {
+	if function.Websocket.Stomp.Destination == "" {
+		return nil, errors.New("missing stomp destination")
+	}
+
+	host := function.Websocket.Stomp.Host
+	if host == "" {
+		parsed, err := url.Parse(u)
+		if err != nil {
+			return nil, err
+		}
+		host = parsed.Hostname()
+	}
+
+	return &stompProtocol{function: function, host: host, token: token}, nil
+}
+
+func (p *stompProtocol) url(u string) (string, error) {
+	return u, nil
+}
+
+// open sends CONNECT frame with heartbeats, credentials and access token
+func (p *stompProtocol) open() [][]byte {
+	config := &p.function.Websocket
+	vars := map[string]string{"token": p.token}
+
+	headers := [][2]string{
+		{"accept-version", "1.2,1.1"},
+		{"host", p.host},
+		{"heart-beat", fmt.Sprintf("%d,%d", common.StompHeartBeatSend, common.StompHeartBeatReceive)},
+	}
+	if config.Stomp.Login != "" {
+		headers = append(headers, [2]string{"login", expand(config.Stomp.Login, vars)})
+	}
+	if config.Stomp.Passcode != "" {
+		headers = append(headers, [2]string{"passcode", expand(config.Stomp.Passcode, vars)})
+	}
+	if wsMessageAuth(p.function) {
+		name := config.AuthName
+		if name == "" {
+			name = common.DefaultWsAuthHeader
+		}
+		template := config.AuthTemplate
+		if template == "" {
+			template = "Bearer ${token}"
+		}
+		headers = append(headers, [2]string{name, expand(template, vars)})
+	}
+	headers = append(headers, sortedHeaders(config.Stomp.Headers, vars)...)
+
+	return [][]byte{stompEncode("CONNECT", headers, nil)}
+}
+
+// decode handles every frame of the message, servers may batch several frames
+// or send heartbeats before a frame
+func (p *stompProtocol) decode(data []byte) ([][]byte, [][]byte, error) {
+	frames, err := stompParse(data)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	payloads, replies := make([][]byte, 0), make([][]byte, 0)
+	for _, frame := range frames {
+		switch frame.command {
+		case "CONNECTED":
+			replies = append(replies, stompEncode("SUBSCRIBE", [][2]string{
+				{"id", "0"},
+				{"destination", p.function.Websocket.Stomp.Destination},
+				{"ack", "auto"},
+			}, nil))
+		case "MESSAGE":
+			payloads = append(payloads, frame.body)
+		case "ERROR":
+			return payloads, replies, fmt.Errorf("stomp error: %s %s", frame.headers["message"], truncate(string(frame.body)))
+		}
+	}
+
+	return payloads, replies, nil
+}
+
+// heartbeat is a single end of line
+func (p *stompProtocol) heartbeat() []byte {
+	return []byte("\n")
+}
+
+// encode sends message to sendDestination or subscribed destination
+func (p *stompProtocol) encode(message []byte) []byte {
+	destination := p.function.Websocket.Stomp.SendDestination
+	if destination == "" {
+		destination = p.function.Websocket.Stomp.Destination
+	}
+
+	return stompEncode("SEND", [][2]string{
+		{"destination", destination},
+		{"content-type", "application/json"},
+		{"content-length", strconv.Itoa(len(message))},
+	}, message)
+}
+
+func sortedHeaders(values map[string]string, vars map[string]string) [][2]string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	headers := make([][2]string, 0, len(keys))
+	for _, key := range keys {
+		headers = append(headers, [2]string{key, expand(values[key], vars)})
+	}
+
+	return headers
+}
+
+func stompEncode(command string, headers [][2]string, body []byte) []byte {
+	var b strings.Builder
+	b.WriteString(command)
+	b.WriteByte('\n')
+	for _, header := range headers {
+		// CONNECT headers are not escaped
+		if command == "CONNECT" {
+			b.WriteString(header[0] + ":" + header[1] + "\n")
+		} else {
+			b.WriteString(stompEscaper.Replace(header[0]) + ":" + stompEscaper.Replace(header[1]) + "\n")
+		}
+	}
+	b.WriteByte('\n')
+	b.Write(body)
+	b.WriteByte(0)
+
+	return []byte(b.String())
+}
+
+// stompParse parses frames of the message. Heartbeat end of lines between
+// frames are skipped, so heartbeat alone returns no frames
+func stompParse(data []byte) ([]*stompFrame, error) {
+	frames := make([]*stompFrame, 0, 1)
+	rest := string(data)
+	for {
+		rest = strings.TrimLeft(rest, "\r\n")
+		if rest == "" {
+			return frames, nil
+		}
+
+		frame, remaining, err := stompParseFrame(rest)
+		if err != nil {
+			return nil, err
+		}
+		frames = append(frames, frame)
+		rest = remaining
+	}
+}
+
+// stompParseFrame parses the first frame of data and returns data after its NUL terminator.
+// Body is read up to content-length when the header is present, so it may contain NUL
+func stompParseFrame(rest string) (*stompFrame, string, error) {
+	frame := &stompFrame{headers: make(map[string]string)}
+	for {
+		i := strings.IndexByte(rest, '\n')
+		if i < 0 {
+			return nil, "", errors.New("stomp: incomplete frame")
+		}
+		line := strings.TrimSuffix(rest[:i], "\r")
+		rest = rest[i+1:]
+
+		if frame.command == "" {
+			frame.command = line
+			continue
+		}
+		if line == "" {
+			break
+		}
+
+		key, value, found := strings.Cut(line, ":")
+		if !found {
+			return nil, "", fmt.Errorf("stomp: invalid header %s", line)
+		}
+		if frame.command != "CONNECTED" {
+			key, value = stompUnescaper.Replace(key), stompUnescaper.Replace(value)
+		}
+		if _, exists := frame.headers[key]; !exists {
+			frame.headers[key] = value
+		}
+	}
+
+	end := strings.IndexByte(rest, 0)
+	if length, err := strconv.Atoi(frame.headers["content-length"]); err == nil && length >= 0 && length < len(rest) && rest[length] == 0 {
+		end = length
+	}
+	// frame without NUL terminator ends with the message
+	if end < 0 {
+		frame.body = []byte(rest)
+		return frame, "", nil
+	}
+	frame.body = []byte(rest[:end])
+
+	return frame, rest[end+1:], nil
+}
@@ -0,0 +1,123 @@
+package healthcheck
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/healthcheck-watchdog/cmd/model"
+)
+
+func TestStompParse(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     string
+		expected []stompFrame
+		failure  string
+	}{
+		{name: "heartbeat", data: "\n", expected: []stompFrame{}},
+		{name: "single", data: "MESSAGE\ndestination:/topic/a\n\n{\"v\":1}\x00",
+			expected: []stompFrame{{command: "MESSAGE", headers: map[string]string{"destination": "/topic/a"}, body: []byte(`{"v":1}`)}}},
+		{name: "heartbeat before frame", data: "\r\n\nMESSAGE\r\nid:1\r\n\r\nA\x00",
+			expected: []stompFrame{{command: "MESSAGE", headers: map[string]string{"id": "1"}, body: []byte("A")}}},
+		{name: "batch", data: "CONNECTED\nversion:1.2\n\n\x00\nMESSAGE\nid:1\n\nA\x00MESSAGE\nid:2\n\nB\x00\n",
+			expected: []stompFrame{
+				{command: "CONNECTED", headers: map[string]string{"version": "1.2"}, body: []byte{}},
+				{command: "MESSAGE", headers: map[string]string{"id": "1"}, body: []byte("A")},
+				{command: "MESSAGE", headers: map[string]string{"id": "2"}, body: []byte("B")},
+			}},
+		{name: "content length", data: "MESSAGE\ncontent-length:3\n\na\x00b\x00MESSAGE\n\nC\x00",
+			expected: []stompFrame{
+				{command: "MESSAGE", headers: map[string]string{"content-length": "3"}, body: []byte("a\x00b")},
+				{command: "MESSAGE", headers: map[string]string{}, body: []byte("C")},
+			}},
+		{name: "escaped headers", data: "MESSAGE\nkey\\cname:a\\\\b\\nc\nkey\\cname:repeated\n\n\x00",
+			expected: []stompFrame{{command: "MESSAGE", headers: map[string]string{"key:name": "a\\b\nc"}, body: []byte{}}}},
+		{name: "connected not escaped", data: "CONNECTED\nserver:a\\cb\n\n\x00",
+			expected: []stompFrame{{command: "CONNECTED", headers: map[string]string{"server": "a\\cb"}, body: []byte{}}}},
+		{name: "without terminator", data: "MESSAGE\n\nA",
+			expected: []stompFrame{{command: "MESSAGE", headers: map[string]string{}, body: []byte("A")}}},
+		{name: "incomplete", data: "MESSAGE\nid:1", failure: "incomplete frame"},
+		{name: "invalid header", data: "MESSAGE\nid\n\n\x00", failure: "invalid header id"},
+	}
+	for _, tt := range tests {
+		frames, err := stompParse([]byte(tt.data))
+		if tt.failure != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.failure) {
+				t.Errorf("%s: expected %q, got %v", tt.name, tt.failure, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.name, err)
+			continue
+		}
+		result := make([]stompFrame, 0, len(frames))
+		for _, frame := range frames {
+			result = append(result, *frame)
+		}
+		if !reflect.DeepEqual(result, tt.expected) {
+			t.Errorf("%s: expected %+v, got %+v", tt.name, tt.expected, result)
+		}
+	}
+}
+
+func TestStompEncode(t *testing.T) {
+	frame := string(stompEncode("SEND", [][2]string{{"destination", "/queue/a:b"}, {"note", "x\ny\\z"}}, []byte("{}")))
+	expected := "SEND\ndestination:/queue/a\\cb\nnote:x\\ny\\\\z\n\n{}\x00"
+	if frame != expected {
+		t.Errorf("expected %q, got %q", expected, frame)
+	}
+
+	frames, err := stompParse([]byte(frame))
+	if err != nil || len(frames) != 1 || frames[0].headers["destination"] != "/queue/a:b" || frames[0].headers["note"] != "x\ny\\z" {
+		t.Errorf("expected headers unescaped, got %+v %v", frames, err)
+	}
+
+	// CONNECT headers are sent as is
+	connect := string(stompEncode("CONNECT", [][2]string{{"passcode", "a:b"}}, nil))
+	if connect != "CONNECT\npasscode:a:b\n\n\x00" {
+		t.Errorf("expected connect headers not escaped, got %q", connect)
+	}
+}
+
+func TestStompDecode(t *testing.T) {
+	function := &model.Job{Websocket: model.Websocket{Protocol: "stomp", Stomp: model.Stomp{Destination: "/topic/ticks"}}}
+	protocol, err := newStompProtocol(function, "ws://broker:61614/ws", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	open := string(protocol.open()[0])
+	if !strings.HasPrefix(open, "CONNECT\n") || !strings.Contains(open, "host:broker\n") || !strings.Contains(open, "heart-beat:20000,10000\n") {
+		t.Errorf("unexpected connect frame %q", open)
+	}
+
+	payloads, replies, err := protocol.decode([]byte("CONNECTED\nversion:1.2\n\n\x00MESSAGE\nid:1\n\n{\"v\":1}\x00"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(replies) != 1 || !strings.HasPrefix(string(replies[0]), "SUBSCRIBE\n") || !strings.Contains(string(replies[0]), "destination:/topic/ticks\n") {
+		t.Errorf("expected subscribe reply, got %q", replies)
+	}
+	if len(payloads) != 1 || string(payloads[0]) != `{"v":1}` {
+		t.Errorf("expected message payload, got %q", payloads)
+	}
+
+	payloads, replies, err = protocol.decode([]byte("\n"))
+	if err != nil || len(payloads) != 0 || len(replies) != 0 {
+		t.Errorf("expected heartbeat ignored, got %q %q %v", payloads, replies, err)
+	}
+
+	payloads, _, err = protocol.decode([]byte("MESSAGE\n\nA\x00ERROR\nmessage:access denied\n\nbad token\x00"))
+	if err == nil || err.Error() != "stomp error: access denied bad token" {
+		t.Errorf("expected stomp error, got %v", err)
+	}
+	if len(payloads) != 1 || string(payloads[0]) != "A" {
+		t.Errorf("expected payload before error, got %q", payloads)
+	}
+
+	if string(protocol.encode([]byte("{}"))) != "SEND\ndestination:/topic/ticks\ncontent-type:application/json\ncontent-length:2\n\n{}\x00" {
+		t.Errorf("unexpected send frame %q", protocol.encode([]byte("{}")))
+	}
+}
@@ -0,0 +1,247 @@
+package healthcheck
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/PaesslerAG/jsonpath"
+	"github.com/healthcheck-watchdog/cmd/common"
+	"github.com/healthcheck-watchdog/cmd/exporter"
+	"github.com/healthcheck-watchdog/cmd/model"
+	log "github.com/sirupsen/logrus"
+)
+
+// streamState keeps connection state, freshness and throughput of a
+// long-lived websocket or sse connection of the job url
+type streamState struct {
+	mx          sync.Mutex
+	function    *model.Job
+	url         string
+	state       string
+	registered  time.Time
+	lastMessage time.Time
+	prevMessage time.Time
+	received    []time.Time
+	interval    time.Duration
+	countRate   bool
+	lastError   error
+	// retry is reconnect delay requested by server, e.g. sse retry field
+	retry time.Duration
+}
+
+func (s *streamState) init(function *model.Job, u string, interval int, countRate bool) {
+	s.function = function
+	s.url = u
+	s.registered = time.Now()
+	s.lastMessage = s.registered
+	s.interval = common.DefaultStreamInterval * time.Second
+	if interval > 0 {
+		s.interval = time.Duration(interval) * time.Second
+	}
+	s.countRate = countRate
+}
+
+// reconnect runs serve until ctx is done with exponential backoff between attempts.
+// Backoff starts from reconnect delay requested by server or StreamBackoffMin and
+// is reset after a connection that received messages
+func (s *streamState) reconnect(ctx context.Context, ex *exporter.Exporter, serve func() (bool, error)) {
+	backoff := s.minBackoff()
+	for {
+		s.setState(ex, common.StreamConnecting, nil)
+		received, err := serve()
+		if ctx.Err() != nil {
+			log.Info(fmt.Sprintf("%s. Closed %s", s.function.Id, s.url))
+			return
+		}
+
+		if received {
+			backoff = s.minBackoff()
+		}
+		log.Error(fmt.Sprintf("%s. Received %s error: %s. Reconnecting in %s", s.function.Id, s.url, err.Error(), backoff))
+		s.setState(ex, common.StreamBackoff, err)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff = nextBackoff(backoff, s.minBackoff())
+
+		ex.IncReconnects(s.function.Id, s.url)
+	}
+}
+
+// nextBackoff doubles backoff up to StreamBackoffMax or up to reconnect delay
+// requested by server when it is longer
+func nextBackoff(backoff time.Duration, min time.Duration) time.Duration {
+	backoff *= 2
+	if limit := max(min, common.StreamBackoffMax*time.Second); backoff > limit {
+		return limit
+	}
+
+	return backoff
+}
+
+func (s *streamState) minBackoff() time.Duration {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	if s.retry > 0 {
+		return s.retry
+	}
+
+	return common.StreamBackoffMin * time.Second
+}
+
+func (s *streamState) setRetry(retry time.Duration) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	s.retry = retry
+}
+
+func (s *streamState) setState(ex *exporter.Exporter, state string, err error) {
+	s.mx.Lock()
+	s.state = state
+	if err != nil {
+		s.lastError = err
+	}
+	s.mx.Unlock()
+
+	ex.SetConnectionState(s.function.Id, s.url, state)
+}
+
+// record registers healthy message
+func (s *streamState) record(ex *exporter.Exporter) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	now := time.Now()
+	if !s.prevMessage.IsZero() {
+		ex.ObserveMessageGap(s.function.Id, s.url, now.Sub(s.prevMessage).Seconds())
+	}
+	s.prevMessage = now
+	s.lastMessage = now
+
+	if s.countRate {
+		s.received = append(s.received, now)
+		s.prune(now)
+	}
+}
+
+// prune drops received times outside of throughput interval
+func (s *streamState) prune(now time.Time) {
+	from := now.Add(-s.interval)
+	i := 0
+	for i < len(s.received) && s.received[i].Before(from) {
+		i++
+	}
+	s.received = s.received[i:]
+}
+
+// messageAge is time since last healthy message or connection registration
+func (s *streamState) messageAge() time.Duration {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	return time.Since(s.lastMessage)
+}
+
+// idle is time without healthy messages since the connection was established,
+// so a new connection is not considered stale immediately
+func (s *streamState) idle(connected time.Time) time.Duration {
+	idle := s.messageAge()
+	if since := time.Since(connected); since < idle {
+		return since
+	}
+
+	return idle
+}
+
+// status returns current state and the last connection error outside connected state
+func (s *streamState) status() (string, error) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	if s.state == common.StreamConnected || s.lastError == nil {
+		return s.state, nil
+	}
+
+	return s.state, s.lastError
+}
+
+// throughput returns number of messages received during the interval.
+// Ready is false until connection is registered for the whole interval
+func (s *streamState) throughput() (int, bool) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	now := time.Now()
+	s.prune(now)
+
+	return len(s.received), now.Sub(s.registered) >= s.interval
+}
+
+// messageLabel extracts JSONPath value of json message used as label of messages count
+func messageLabel(path string, message []byte) string {
+	if path == "" {
+		return ""
+	}
+
+	var data interface{}
+	if err := json.Unmarshal(message, &data); err != nil {
+		return ""
+	}
+	value, err := jsonpath.Get(path, data)
+	if err != nil || value == nil {
+		return ""
+	}
+
+	return jsonString(value)
+}
+
+// checkFreshness fails when the last message is older than job timeout
+func checkFreshness(kind string, s *streamState) error {
+	difference := int64(s.messageAge().Seconds())
+	if difference > s.function.Timeout {
+		if state, err := s.status(); err != nil {
+			return connectionFailure(err, fmt.Errorf("%s last message on url %s exceeded timeout: %ds, connection %s: %s", kind, s.url, difference, state, err.Error()))
+		}
+		return fmt.Errorf("%s last message on url %s exceeded timeout: %ds", kind, s.url, difference)
+	}
+
+	return nil
+}
+
+// checkThroughput expects at least minMessages during the interval
+func (hc *HealthCheck) checkThroughput(kind string, s *streamState, minMessages int) error {
+	count, ready := s.throughput()
+	hc.exporter.SetMessageRate(s.function.Id, s.url, float64(count)/s.interval.Seconds())
+
+	if count >= minMessages {
+		return nil
+	}
+	if !ready {
+		return unknown(fmt.Errorf("%s collecting messages on url %s: %d in %s", kind, s.url, count, s.interval))
+	}
+	if state, err := s.status(); err != nil {
+		return connectionFailure(err, fmt.Errorf("%s received %d messages on url %s in %s, expected at least %d, connection %s: %s",
+			kind, count, s.url, s.interval, minMessages, state, err.Error()))
+	}
+
+	return fmt.Errorf("%s received %d messages on url %s in %s, expected at least %d",
+		kind, count, s.url, s.interval, minMessages)
+}
+
+// connectionFailure keeps failure unknown when connection failed on watchdog
+// side, e.g. on access token
+func connectionFailure(connErr error, err error) error {
+	if isUnknown(connErr) {
+		return unknown(err)
+	}
+
+	return err
+}
@@ -0,0 +1,188 @@
+package healthcheck
+
+import (
+	"bufio"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/textproto"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/healthcheck-watchdog/cmd/common"
+	"github.com/healthcheck-watchdog/cmd/model"
+	log "github.com/sirupsen/logrus"
+)
+
+// checkTcp connects to each host:port from urls, optionally upgrades
+// connection to TLS and matches response to the sent payload
+func (hc *HealthCheck) checkTcp(function *model.Job) error {
+	timeout := time.Duration(function.ResponseTimeout) * time.Second
+	if timeout <= 0 {
+		timeout = common.DefaultTcpTimeout * time.Second
+	}
+
+	var expect *regexp.Regexp
+	if function.Tcp.ExpectRegex != "" {
+		var err error
+		expect, err = regexp.Compile(function.Tcp.ExpectRegex)
+		if err != nil {
+			return unknown(fmt.Errorf("invalid expect regex: %w", err))
+		}
+	}
+
+	var latency time.Duration
+	for _, address := range function.Urls {
+		start := time.Now()
+		conn, err := net.DialTimeout("tcp", address, timeout)
+		if err != nil {
+			return fmt.Errorf("tcp connect to %s: %s", address, err.Error())
+		}
+		latency += time.Since(start)
+
+		err = tcpExchange(conn, address, function, expect, timeout)
+		conn.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	hc.exporter.SetGauge(function.Id, float64(latency.Milliseconds()))
+	log.Info(fmt.Sprintf("%s %s", function.Id, latency))
+
+	return nil
+}
+
+func tcpExchange(conn net.Conn, address string, function *model.Job, expect *regexp.Regexp, timeout time.Duration) error {
+	err := conn.SetDeadline(time.Now().Add(timeout))
+	if err != nil {
+		return err
+	}
+
+	switch function.Tcp.Tls {
+	case "":
+	case common.TcpTlsDirect:
+		conn, err = tcpTls(conn, address, function)
+		if err != nil {
+			return err
+		}
+	case common.TcpTlsStartTls:
+		if err := tcpStartTls(conn, address, function); err != nil {
+			return err
+		}
+		conn, err = tcpTls(conn, address, function)
+		if err != nil {
+			return err
+		}
+	default:
+		return unknown(fmt.Errorf("unsupported tcp tls mode %s", function.Tcp.Tls))
+	}
+
+	if function.Tcp.Send != "" {
+		if _, err := conn.Write([]byte(function.Tcp.Send)); err != nil {
+			return fmt.Errorf("send to %s: %s", address, err.Error())
+		}
+	}
+
+	if function.Tcp.Expect == "" && expect == nil {
+		return nil
+	}
+
+	containsExpected := func(response string) bool {
+		return strings.Contains(response, function.Tcp.Expect) && (expect == nil || expect.MatchString(response))
+	}
+	response, err := tcpRead(conn, containsExpected)
+	if err != nil {
+		return fmt.Errorf("read from %s: %s", address, err.Error())
+	}
+	if function.Tcp.Expect != "" && !strings.Contains(response, function.Tcp.Expect) {
+		return fmt.Errorf("response from %s doesn't contain %q", address, function.Tcp.Expect)
+	}
+	if expect != nil && !expect.MatchString(response) {
+		return fmt.Errorf("response from %s doesn't match %q", address, function.Tcp.ExpectRegex)
+	}
+
+	return nil
+}
+
+// tcpStartTls reads greeting, then sends upgrade commands reading a reply to each.
+// Replies are SMTP-like: greeting code must be 220 and command replies 2xx,
+// multi-line replies like 250-... are read up to the last line
+func tcpStartTls(conn net.Conn, address string, function *model.Job) error {
+	reader := textproto.NewReader(bufio.NewReader(conn))
+	if _, _, err := reader.ReadResponse(common.StartTlsGreetingCode); err != nil {
+		return fmt.Errorf("starttls greeting from %s: %s", address, startTlsReply(err))
+	}
+
+	commands := function.Tcp.StartTls
+	if len(commands) == 0 {
+		commands = common.DefaultStartTls
+	}
+	for _, cmd := range commands {
+		if _, err := conn.Write([]byte(cmd)); err != nil {
+			return fmt.Errorf("send starttls command to %s: %s", address, err.Error())
+		}
+		if _, _, err := reader.ReadResponse(common.StartTlsReplyCode); err != nil {
+			return fmt.Errorf("starttls command %s to %s: %s", strings.TrimSpace(cmd), address, startTlsReply(err))
+		}
+	}
+
+	return nil
+}
+
+// startTlsReply formats unexpected reply of the server as code and text
+func startTlsReply(err error) string {
+	var reply *textproto.Error
+	if errors.As(err, &reply) {
+		return fmt.Sprintf("%d %s", reply.Code, reply.Msg)
+	}
+
+	return err.Error()
+}
+
+func tcpTls(conn net.Conn, address string, function *model.Job) (net.Conn, error) {
+	serverName := function.Tcp.ServerName
+	if serverName == "" {
+		host, _, err := net.SplitHostPort(address)
+		if err != nil {
+			return nil, err
+		}
+		serverName = host
+	}
+
+	tlsConn := tls.Client(conn, &tls.Config{
+		ServerName:         serverName,
+		InsecureSkipVerify: function.Tcp.InsecureSkipVerify, //nolint:gosec // opt-in by configuration
+	})
+	if err := tlsConn.Handshake(); err != nil {
+		return nil, fmt.Errorf("tls handshake with %s: %s", address, err.Error())
+	}
+
+	return tlsConn, nil
+}
+
+// tcpRead reads data until it matches, TcpReadLimit is reached, connection
+// is closed or deadline passes. Response may arrive in several chunks, e.g.
+// multi-line banner
+func tcpRead(conn net.Conn, matches func(string) bool) (string, error) {
+	buf := make([]byte, common.TcpReadLimit)
+	n := 0
+	for n < len(buf) {
+		read, err := conn.Read(buf[n:])
+		n += read
+		if err != nil {
+			// data read before error is evaluated by caller
+			if n == 0 {
+				return "", err
+			}
+			break
+		}
+		if matches(string(buf[:n])) {
+			break
+		}
+	}
+
+	return string(buf[:n]), nil
+}
@@ -0,0 +1,143 @@
+package healthcheck
+
+import (
+	"bufio"
+	"crypto/tls"
+	"net"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/healthcheck-watchdog/cmd/common"
+	"github.com/healthcheck-watchdog/cmd/exporter"
+	"github.com/healthcheck-watchdog/cmd/model"
+)
+
+// smtpServer answers EHLO with multi-line reply and STARTTLS with starttlsReply,
+// then upgrades connection to TLS on 220 reply
+func smtpServer(t *testing.T, starttlsReply string) string {
+	t.Helper()
+	tlsServer := httptest.NewTLSServer(nil)
+	certificates := tlsServer.TLS.Certificates
+	tlsServer.Close()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		reader := bufio.NewReader(conn)
+		conn.Write([]byte("220 mx ESMTP\r\n")) //nolint:errcheck // test server
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				return
+			}
+			switch {
+			case strings.HasPrefix(line, "EHLO"):
+				conn.Write([]byte("250-mx\r\n250-SIZE 1000\r\n250 STARTTLS\r\n")) //nolint:errcheck // test server
+			case strings.HasPrefix(line, "STARTTLS"):
+				conn.Write([]byte(starttlsReply)) //nolint:errcheck // test server
+				if strings.HasPrefix(starttlsReply, "220") {
+					tls.Server(conn, &tls.Config{Certificates: certificates}).Handshake() //nolint:errcheck // test server
+				}
+				return
+			}
+		}
+	}()
+
+	return listener.Addr().String()
+}
+
+func startTls(t *testing.T, address string) error {
+	t.Helper()
+	conn, err := net.Dial("tcp", address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	function := &model.Job{Id: "t", Tcp: model.Tcp{Tls: common.TcpTlsStartTls, InsecureSkipVerify: true}}
+	return tcpExchange(conn, address, function, nil, 5*time.Second)
+}
+
+func TestTcpStartTls(t *testing.T) {
+	if err := startTls(t, smtpServer(t, "220 ready to start TLS\r\n")); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestTcpStartTlsRejected(t *testing.T) {
+	for _, reply := range []string{"454 TLS not available\r\n", "502 command not implemented\r\n"} {
+		err := startTls(t, smtpServer(t, reply))
+		if err == nil || !strings.Contains(err.Error(), strings.TrimSpace(reply)) {
+			t.Errorf("expected failure with %q, got %v", strings.TrimSpace(reply), err)
+		}
+	}
+}
+
+// bannerServer writes banner in chunks with a pause and keeps connection open
+func bannerServer(t *testing.T, chunks ...string) string {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				for _, chunk := range chunks {
+					conn.Write([]byte(chunk)) //nolint:errcheck // test server
+					time.Sleep(100 * time.Millisecond)
+				}
+				time.Sleep(time.Second)
+			}()
+		}
+	}()
+
+	return listener.Addr().String()
+}
+
+func TestTcpReadChunks(t *testing.T) {
+	address := bannerServer(t, "220-mx.test ESMTP\r\n", "220 ready\r\n")
+
+	tests := []struct {
+		name    string
+		tcp     model.Tcp
+		failure string
+	}{
+		{name: "expect", tcp: model.Tcp{Expect: "220 ready"}},
+		{name: "expect regex", tcp: model.Tcp{ExpectRegex: `(?m)^220 `}},
+		// deadline passes waiting for the rest of response
+		{name: "mismatch", tcp: model.Tcp{Expect: "250"}, failure: `doesn't contain "250"`},
+	}
+	for _, tt := range tests {
+		function := &model.Job{Id: "t", Type: "tcp", Urls: []string{address}, ResponseTimeout: 1, Tcp: tt.tcp}
+		hc := &HealthCheck{exporter: &exporter.Exporter{}}
+		err := hc.checkTcp(function)
+		if tt.failure == "" {
+			if err != nil {
+				t.Errorf("%s: unexpected error: %v", tt.name, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.failure) {
+			t.Errorf("%s: expected %q, got %v", tt.name, tt.failure, err)
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package healthcheck
+
+import (
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/healthcheck-watchdog/cmd/common"
+)
+
+var templatePattern = regexp.MustCompile(`\$\{([^}]+)\}`)
+
+// expand replaces ${name} placeholders with variables, built-in values
+// (now, unix, unixMilli) or environment variables prefixed with WATCHDOG_, so
+// other variables of the process can't leak into requests. Unresolved placeholders are kept as is
+func expand(value string, vars map[string]string) string {
+	return templatePattern.ReplaceAllStringFunc(value, func(match string) string {
+		name := match[2 : len(match)-1]
+		if v, found := vars[name]; found {
+			return v
+		}
+
+		switch name {
+		case "now":
+			return time.Now().UTC().Format(time.RFC3339)
+		case "unix":
+			return strconv.FormatInt(time.Now().Unix(), 10)
+		case "unixMilli":
+			return strconv.FormatInt(time.Now().UnixMilli(), 10)
+		}
+
+		if strings.HasPrefix(name, common.TemplateEnvPrefix) {
+			if v, found := os.LookupEnv(name); found {
+				return v
+			}
+		}
+
+		return match
+	})
+}
@@ -0,0 +1,23 @@
+package healthcheck
+
+import "testing"
+
+func TestExpand(t *testing.T) {
+	t.Setenv("WATCHDOG_API_KEY", "key")
+	t.Setenv("DB_PASSWORD", "secret")
+
+	tests := []struct {
+		value    string
+		expected string
+	}{
+		{value: "id=${id}", expected: "id=42"},
+		{value: "key=${WATCHDOG_API_KEY}", expected: "key=key"},
+		{value: "password=${DB_PASSWORD}", expected: "password=${DB_PASSWORD}"},
+		{value: "${WATCHDOG_MISSING}", expected: "${WATCHDOG_MISSING}"},
+	}
+	for _, tt := range tests {
+		if actual := expand(tt.value, map[string]string{"id": "42"}); actual != tt.expected {
+			t.Errorf("expand(%q) = %q, expected %q", tt.value, actual, tt.expected)
+		}
+	}
+}
@@ -0,0 +1,170 @@
+package healthcheck
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/healthcheck-watchdog/cmd/common"
+	"github.com/healthcheck-watchdog/cmd/model"
+	log "github.com/sirupsen/logrus"
+)
+
+// checkTls connects to each url and validates peer certificate chain
+func (hc *HealthCheck) checkTls(function *model.Job) error {
+	for _, u := range function.Urls {
+		err := hc.probeTls(function, u)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// probeTls makes tls handshake with the url host and inspects peer certificates.
+// Chain is verified after handshake so certificate metrics are exported even for untrusted chain
+func (hc *HealthCheck) probeTls(function *model.Job, u string) error {
+	address, err := tlsAddress(u)
+	if err != nil {
+		return err
+	}
+
+	serverName := function.Tls.ServerName
+	if serverName == "" {
+		serverName, _, _ = net.SplitHostPort(address)
+	}
+
+	timeout := checkTimeout(function)
+
+	start := time.Now()
+	dialer := &tls.Dialer{
+		NetDialer: &net.Dialer{Timeout: timeout},
+		Config: &tls.Config{
+			ServerName:         serverName,
+			InsecureSkipVerify: true, //nolint:gosec // chain is verified in inspectTls
+		},
+	}
+	conn, err := dialer.Dial("tcp", address)
+	if err != nil {
+		return fmt.Errorf("tls handshake with %s: %s", address, err.Error())
+	}
+	defer conn.Close()
+
+	if function.Type == "tls" {
+		hc.exporter.SetGauge(function.Id, float64(time.Since(start).Milliseconds()))
+	}
+
+	state := conn.(*tls.Conn).ConnectionState()
+
+	return hc.inspectTls(function, serverName, &state, true)
+}
+
+// inspectTls exports certificate details and validates expiry, protocol version
+// and certificate chain and hostname unless they were verified on handshake
+func (hc *HealthCheck) inspectTls(function *model.Job, serverName string, state *tls.ConnectionState, verify bool) error {
+	if len(state.PeerCertificates) == 0 {
+		return fmt.Errorf("no peer certificates presented by %s", serverName)
+	}
+
+	leaf := state.PeerCertificates[0]
+	days := time.Until(leaf.NotAfter).Hours() / 24
+	hc.exporter.SetCertificate(function.Id, serverName, days,
+		leaf.Subject.String(), leaf.Issuer.String(), strings.Join(leaf.DNSNames, ","))
+	log.Debug(fmt.Sprintf("%s: certificate of %s expires in %.1f days", function.Id, serverName, days))
+
+	if verify {
+		intermediates := x509.NewCertPool()
+		for _, cert := range state.PeerCertificates[1:] {
+			intermediates.AddCert(cert)
+		}
+
+		// ca bundle of requests is trusted by tls inspection as well
+		caFile := function.Tls.CaFile
+		if caFile == "" {
+			caFile = function.Request.CaFile
+		}
+		roots, err := loadCaFile(caFile)
+		if err != nil {
+			return unknown(err)
+		}
+
+		_, err = leaf.Verify(x509.VerifyOptions{Roots: roots, Intermediates: intermediates})
+		if err != nil {
+			return fmt.Errorf("untrusted certificate chain of %s: %s", serverName, err.Error())
+		}
+
+		err = leaf.VerifyHostname(serverName)
+		if err != nil {
+			return fmt.Errorf("certificate hostname mismatch: %s", err.Error())
+		}
+	}
+
+	if function.Tls.MinDaysValid > 0 && days < function.Tls.MinDaysValid {
+		return fmt.Errorf("certificate of %s expires in %.1f days, expected at least %.1f",
+			serverName, days, function.Tls.MinDaysValid)
+	}
+
+	if function.Tls.MinVersion != "" {
+		version, found := common.TlsVersions[function.Tls.MinVersion]
+		if !found {
+			return unknown(fmt.Errorf("unsupported minimal tls version %s", function.Tls.MinVersion))
+		}
+		if state.Version < version {
+			return fmt.Errorf("tls version %s of %s is below %s",
+				tls.VersionName(state.Version), serverName, tls.VersionName(version))
+		}
+	}
+
+	return nil
+}
+
+// loadCaFile returns pool with certificates from PEM file, nil for system roots
+func loadCaFile(path string) (*x509.CertPool, error) {
+	if path == "" {
+		return nil, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("load ca file: %w", err)
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("no certificates found in ca file %s", path)
+	}
+
+	return pool, nil
+}
+
+// tlsAddress converts https/wss url or host[:port] into dial address
+func tlsAddress(u string) (string, error) {
+	if !strings.Contains(u, "://") {
+		if _, _, err := net.SplitHostPort(u); err != nil {
+			return net.JoinHostPort(u, common.DefaultTlsPort), nil
+		}
+		return u, nil
+	}
+
+	parsed, err := url.Parse(u)
+	if err != nil {
+		return "", err
+	}
+	if parsed.Hostname() == "" {
+		return "", errors.New("missing host in url " + u)
+	}
+
+	port := parsed.Port()
+	if port == "" {
+		port = common.DefaultTlsPort
+	}
+
+	return net.JoinHostPort(parsed.Hostname(), port), nil
+}
@@ -0,0 +1,95 @@
+package healthcheck
+
+import (
+	"crypto/tls"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/healthcheck-watchdog/cmd/exporter"
+	"github.com/healthcheck-watchdog/cmd/model"
+)
+
+// tlsServer starts https server limited to maxVersion and returns it with
+// a PEM file of its certificate
+func tlsServer(t *testing.T, maxVersion uint16) (*httptest.Server, string) {
+	t.Helper()
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	server.TLS = &tls.Config{MaxVersion: maxVersion}
+	server.StartTLS()
+	t.Cleanup(server.Close)
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	data := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	if err := os.WriteFile(caFile, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	return server, caFile
+}
+
+func TestProbeTls(t *testing.T) {
+	server, caFile := tlsServer(t, tls.VersionTLS12)
+
+	tests := []struct {
+		name    string
+		tls     model.Tls
+		failure string
+	}{
+		{name: "trusted", tls: model.Tls{CaFile: caFile, MinDaysValid: 30, MinVersion: "1.2"}},
+		{name: "untrusted chain", tls: model.Tls{}, failure: "untrusted certificate chain"},
+		{name: "hostname", tls: model.Tls{CaFile: caFile, ServerName: "api.test"}, failure: "hostname mismatch"},
+		{name: "expiry", tls: model.Tls{CaFile: caFile, MinDaysValid: 1e6}, failure: "expected at least"},
+		{name: "min version", tls: model.Tls{CaFile: caFile, MinVersion: "1.3"}, failure: "tls version TLS 1.2 of 127.0.0.1 is below TLS 1.3"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hc := &HealthCheck{exporter: &exporter.Exporter{}}
+			err := hc.checkTls(&model.Job{Id: "t", Type: "tls", Urls: []string{server.URL}, Tls: tt.tls})
+			if tt.failure == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || isUnknown(err) || !strings.Contains(err.Error(), tt.failure) {
+				t.Errorf("expected %q, got %v", tt.failure, err)
+			}
+		})
+	}
+}
+
+func TestCheckHttpVerifiesSkippedChain(t *testing.T) {
+	server, caFile := tlsServer(t, 0)
+
+	tests := []struct {
+		name    string
+		tls     model.Tls
+		failure string
+	}{
+		{name: "disabled", tls: model.Tls{}},
+		{name: "untrusted chain", tls: model.Tls{Enabled: true}, failure: "untrusted certificate chain"},
+		{name: "hostname", tls: model.Tls{Enabled: true, CaFile: caFile, ServerName: "api.test"}, failure: "hostname mismatch"},
+		{name: "trusted", tls: model.Tls{Enabled: true, CaFile: caFile}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hc := &HealthCheck{exporter: &exporter.Exporter{}, httpClient: NewHttpClient()}
+			err := hc.checkHttp(&model.Job{Id: "h-" + tt.name, Type: "http", Urls: []string{server.URL},
+				Request: model.Request{InsecureSkipVerify: true}, Tls: tt.tls})
+			if tt.failure == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.failure) {
+				t.Errorf("expected %q, got %v", tt.failure, err)
+			}
+		})
+	}
+}
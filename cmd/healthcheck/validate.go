@@ -0,0 +1,427 @@
+package healthcheck
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/PaesslerAG/jsonpath"
+	"github.com/antchfx/xpath"
+	"github.com/healthcheck-watchdog/cmd/common"
+	"github.com/healthcheck-watchdog/cmd/model"
+	"github.com/healthcheck-watchdog/cmd/redis"
+	"github.com/healthcheck-watchdog/cmd/slo"
+	"github.com/miekg/dns"
+)
+
+// validateConfig rejects job configuration which can never be checked,
+// so it fails at startup instead of on every check
+func validateConfig(config *model.Config) error {
+	for i := range config.Jobs {
+		if err := validateJob(&config.Jobs[i]); err != nil {
+			return fmt.Errorf("job %s: %w", config.Jobs[i].Id, err)
+		}
+	}
+
+	return nil
+}
+
+func validateJob(function *model.Job) error {
+	// tls settings apply to tls jobs and http or websocket jobs with tls enabled
+	if _, found := common.TlsVersions[function.Tls.MinVersion]; function.Tls.MinVersion != "" && !found {
+		return fmt.Errorf("unsupported minimal tls version %s", function.Tls.MinVersion)
+	}
+
+	if function.Slo.Window != "" {
+		window, err := slo.ParseWindow(function.Slo.Window)
+		if err != nil || window.Seconds() < common.SloBucketSize {
+			return fmt.Errorf("invalid slo window %s", function.Slo.Window)
+		}
+	}
+
+	switch function.Type {
+	case "http", "http_get", "http_post":
+		if err := validateRequest(&function.Request); err != nil {
+			return err
+		}
+		return validateAssertions(function.Assertions)
+	case "promql":
+		return validatePromql(function)
+	case "websocket":
+		// without assertions any message, e.g. a broadcast, would count as the reply
+		if function.Websocket.Mode == common.ModeRequest && len(function.Websocket.Assertions) == 0 {
+			return errors.New("websocket request mode requires assertions selecting the reply, e.g. on ${requestId}")
+		}
+		if err := validateWsProtocol(&function.Websocket); err != nil {
+			return err
+		}
+		return validateMessageAssertions(function.Websocket.Assertions)
+	case "sse":
+		// request mode needs a message to send, it is websocket only
+		switch function.Sse.Mode {
+		case "", common.ModeStream, common.ModeThroughput:
+		default:
+			return fmt.Errorf("unsupported sse mode %s", function.Sse.Mode)
+		}
+		return validateMessageAssertions(function.Sse.Assertions)
+	case "scenario":
+		return validateScenario(&function.Scenario)
+	case "redis":
+		return redis.Validate(function.Urls, &function.Redis)
+	case "tcp":
+		return validateTcp(&function.Tcp)
+	case "dns":
+		return validateDns(function)
+	case "grpc":
+		return validateGrpc(function)
+	case "sql":
+		return validateSql(&function.Sql)
+	case "exec":
+		if function.Exec.Command == "" {
+			return errors.New("missing exec command")
+		}
+	case "resources":
+		if _, err := resourceMax(common.ResourceCpu, function.Resources.Cpu); err != nil {
+			return err
+		}
+		if _, err := resourceMax(common.ResourceMemory, function.Resources.Memory); err != nil {
+			return err
+		}
+	case "kubernetes":
+		return validateKubernetes(&function.Kubernetes)
+	case "cronjob":
+		if function.CronJob.Name == "" {
+			return errors.New("missing cronjob name")
+		}
+	case "logs":
+		return validateLogs(&function.Logs)
+	}
+
+	return nil
+}
+
+// validateScenario rejects steps which would fail on every run. Transport of
+// steps is configured by job request block, http steps may override redirect policy
+func validateScenario(config *model.Scenario) error {
+	if len(config.Steps) == 0 {
+		return errors.New("missing scenario steps")
+	}
+
+	for i := range config.Steps {
+		step := &config.Steps[i]
+		if err := validateStep(step); err != nil {
+			return fmt.Errorf("step %s: %w", stepName(step, i), err)
+		}
+	}
+
+	return nil
+}
+
+func validateStep(step *model.ScenarioStep) error {
+	if step.Url == "" {
+		return errors.New("missing url")
+	}
+
+	request := &step.Request
+	if request.CaFile != "" || request.InsecureSkipVerify || request.ServerName != "" ||
+		request.DisableKeepAlives || request.Proxy != "" || request.HttpVersion != "" {
+		return errors.New("caFile, insecureSkipVerify, serverName, disableKeepAlives, proxy and httpVersion are set in job request block")
+	}
+
+	switch step.Type {
+	case "", common.StepHttp:
+	case common.StepWebsocket:
+		if request.FollowRedirects != nil || request.MaxRedirects > 0 {
+			return errors.New("websocket step doesn't follow redirects")
+		}
+		// upgrade request is GET, the body is sent as the first message
+		if request.Method != "" || len(request.Form) > 0 {
+			return errors.New("websocket step doesn't support method and form")
+		}
+	default:
+		return fmt.Errorf("unsupported step type %s", step.Type)
+	}
+
+	if err := validateAssertions(step.Assertions); err != nil {
+		return err
+	}
+
+	for _, e := range step.Extract {
+		if e.Variable == "" {
+			return errors.New("missing extract variable")
+		}
+		var err error
+		switch e.Type {
+		case common.ExtractHeader:
+		case common.ExtractJsonPath:
+			_, err = jsonpath.New(e.Path)
+		case common.ExtractRegex:
+			_, err = regexp.Compile(e.Pattern)
+		default:
+			err = fmt.Errorf("unsupported extract type %s", e.Type)
+		}
+		if err != nil {
+			return fmt.Errorf("extract %s: %w", e.Variable, err)
+		}
+	}
+
+	return nil
+}
+
+func validateAssertions(assertions []model.Assertion) error {
+	for i := range assertions {
+		if err := validateAssertion(&assertions[i]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func validateAssertion(a *model.Assertion) error {
+	var err error
+	switch a.Type {
+	case common.AssertionStatus:
+		err = validateCodes(a.Codes)
+	case common.AssertionHeader:
+		if a.Name == "" {
+			err = errors.New("missing header name")
+		}
+	case common.AssertionBody:
+	case common.AssertionBodySize:
+		if a.Max <= 0 {
+			err = errors.New("max must be positive")
+		}
+	case common.AssertionJsonPath:
+		_, err = jsonpath.New(a.Path)
+	case common.AssertionXPath:
+		_, err = xpath.Compile(a.Path)
+	default:
+		return fmt.Errorf("unsupported assertion type %s", a.Type)
+	}
+	if err == nil {
+		err = validateOperator(a)
+	}
+	if err != nil && a.Path != "" {
+		return fmt.Errorf("%s assertion %s: %w", a.Type, a.Path, err)
+	}
+	if err != nil {
+		return fmt.Errorf("%s assertion: %w", a.Type, err)
+	}
+
+	return nil
+}
+
+// validateCodes accepts exact codes (200), classes (2xx) and ranges (200-299)
+func validateCodes(codes []string) error {
+	if len(codes) == 0 {
+		return errors.New("missing codes")
+	}
+
+	for _, code := range codes {
+		valid := false
+		if from, to, found := strings.Cut(code, "-"); found {
+			min, err1 := strconv.Atoi(strings.TrimSpace(from))
+			max, err2 := strconv.Atoi(strings.TrimSpace(to))
+			valid = err1 == nil && err2 == nil && min <= max
+		} else if len(code) == 3 && strings.HasSuffix(strings.ToLower(code), "xx") {
+			valid = code[0] >= '1' && code[0] <= '5'
+		} else {
+			_, err := strconv.Atoi(code)
+			valid = err == nil && len(code) == 3
+		}
+		if !valid {
+			return fmt.Errorf("invalid code %s", code)
+		}
+	}
+
+	return nil
+}
+
+// validateOperator rejects unknown operators and invalid regex. Values with
+// ${variables} are expanded before comparison, so their regex is checked on evaluation
+func validateOperator(a *model.Assertion) error {
+	switch a.Operator {
+	case "", "exists", "==", "!=", "contains", ">", ">=", "<", "<=":
+	case "matches":
+		if !strings.Contains(a.Value, "${") {
+			if _, err := regexp.Compile(a.Value); err != nil {
+				return fmt.Errorf("invalid regex %s", a.Value)
+			}
+		}
+	default:
+		return fmt.Errorf("unsupported operator %s", a.Operator)
+	}
+
+	return nil
+}
+
+func validateTcp(config *model.Tcp) error {
+	switch config.Tls {
+	case "", common.TcpTlsDirect, common.TcpTlsStartTls:
+	default:
+		return fmt.Errorf("unsupported tcp tls mode %s", config.Tls)
+	}
+
+	if _, err := regexp.Compile(config.ExpectRegex); err != nil {
+		return fmt.Errorf("invalid expect regex: %w", err)
+	}
+
+	return nil
+}
+
+func validateDns(function *model.Job) error {
+	config := &function.Dns
+	if config.Name == "" {
+		return errors.New("missing dns name")
+	}
+	if _, found := dns.StringToType[strings.ToUpper(config.RecordType)]; config.RecordType != "" && !found {
+		return fmt.Errorf("unsupported dns record type %s", config.RecordType)
+	}
+	if _, found := dns.StringToRcode[strings.ToUpper(config.Rcode)]; config.Rcode != "" && !found {
+		return fmt.Errorf("unsupported dns rcode %s", config.Rcode)
+	}
+
+	switch config.Protocol {
+	case "", "udp", "tcp", "tcp-tls":
+	default:
+		return fmt.Errorf("unsupported dns protocol %s", config.Protocol)
+	}
+
+	if config.Resolver != "" {
+		_, port, err := net.SplitHostPort(dnsResolver(function))
+		if err == nil {
+			_, err = strconv.ParseUint(port, 10, 16)
+		}
+		if err != nil {
+			return fmt.Errorf("invalid dns resolver %s", config.Resolver)
+		}
+	}
+
+	return nil
+}
+
+func validateGrpc(function *model.Job) error {
+	if len(function.Urls) == 0 {
+		return errors.New("missing grpc targets in urls")
+	}
+	if function.Grpc.InsecureSkipVerify && !function.Grpc.Tls {
+		return errors.New("grpc insecureSkipVerify requires tls")
+	}
+
+	return nil
+}
+
+// validateSql rejects unknown driver and missing query. Connection string is
+// resolved on the first check, environment and secret file may change until then
+func validateSql(config *model.Sql) error {
+	if !contains(sql.Drivers(), config.Driver) {
+		return fmt.Errorf("unsupported sql driver %s", config.Driver)
+	}
+	if config.ConnectionString == "" && config.ConnectionStringEnv == "" && config.ConnectionStringFile == "" {
+		return errors.New("missing sql connection string")
+	}
+	if config.Query == "" {
+		return errors.New("missing sql query")
+	}
+
+	return nil
+}
+
+func validateKubernetes(config *model.Kubernetes) error {
+	for _, w := range config.Workloads {
+		switch strings.ToLower(w.Kind) {
+		case common.KindDeployment, common.KindStatefulSet, common.KindDaemonSet:
+		default:
+			return fmt.Errorf("unsupported workload kind %s", w.Kind)
+		}
+		if w.Name == "" {
+			return fmt.Errorf("missing %s name", w.Kind)
+		}
+	}
+	for _, service := range config.Services {
+		if service == "" {
+			return errors.New("missing service name")
+		}
+	}
+
+	return nil
+}
+
+func validateLogs(config *model.Logs) error {
+	if len(config.Patterns) == 0 {
+		return errors.New("missing log patterns")
+	}
+	for _, p := range config.Patterns {
+		if _, err := regexp.Compile(p.Regex); err != nil {
+			return fmt.Errorf("invalid log pattern %s: %w", p.Regex, err)
+		}
+	}
+
+	return nil
+}
+
+func validateRequest(config *model.Request) error {
+	switch config.HttpVersion {
+	case "", common.HttpVersion1:
+		return nil
+	case common.HttpVersion2:
+		// http2 transport dials the target directly
+		if config.Proxy != "" {
+			return fmt.Errorf("proxy is not supported with http version %s", config.HttpVersion)
+		}
+		return nil
+	}
+
+	return fmt.Errorf("unsupported http version %s", config.HttpVersion)
+}
+
+func validateWsProtocol(config *model.Websocket) error {
+	switch config.Auth {
+	case "", common.WsAuthMessage, common.WsAuthHeader, common.WsAuthQuery:
+	default:
+		return fmt.Errorf("unsupported websocket auth %s", config.Auth)
+	}
+
+	switch config.Protocol {
+	case "", common.WsProtocolRaw, common.WsProtocolSocketIo:
+	case common.WsProtocolStomp:
+		if config.Stomp.Destination == "" {
+			return errors.New("missing stomp destination")
+		}
+	default:
+		return fmt.Errorf("unsupported websocket protocol %s", config.Protocol)
+	}
+
+	return nil
+}
+
+// validateMessageAssertions rejects status and header assertions, stream
+// messages have neither
+func validateMessageAssertions(assertions []model.Assertion) error {
+	for _, a := range assertions {
+		if a.Type == common.AssertionStatus || a.Type == common.AssertionHeader {
+			return fmt.Errorf("%s assertion is not supported on stream messages", a.Type)
+		}
+	}
+
+	return validateAssertions(assertions)
+}
+
+func validatePromql(function *model.Job) error {
+	config := &function.Promql
+	if config.Url == "" || config.Query == "" {
+		return errors.New("missing promql url or query")
+	}
+	switch config.OnEmpty {
+	case "", common.StatusUp, common.StatusDown, common.StatusUnknown:
+	default:
+		return fmt.Errorf("unsupported promql onEmpty %s", config.OnEmpty)
+	}
+
+	return validateRequest(&function.Request)
+}
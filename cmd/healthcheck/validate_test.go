@@ -0,0 +1,158 @@
+package healthcheck
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/healthcheck-watchdog/cmd/model"
+)
+
+func TestValidateStreamJobs(t *testing.T) {
+	body := []model.Assertion{{Type: "jsonpath", Path: "$.type", Value: "tick"}}
+	status := []model.Assertion{{Type: "status", Codes: []string{"200"}}}
+	header := []model.Assertion{{Type: "header", Name: "Content-Type", Value: "json"}}
+
+	tests := []struct {
+		job     model.Job
+		failure string
+	}{
+		{job: model.Job{Id: "ws", Type: "websocket", Websocket: model.Websocket{Assertions: body}}},
+		{job: model.Job{Id: "ws", Type: "websocket", Websocket: model.Websocket{Assertions: status}}, failure: "job ws: status assertion is not supported on stream messages"},
+		{job: model.Job{Id: "sse", Type: "sse", Sse: model.Sse{Assertions: header}}, failure: "job sse: header assertion is not supported on stream messages"},
+		{job: model.Job{Id: "sse", Type: "sse", Sse: model.Sse{Mode: "throughput"}}},
+		{job: model.Job{Id: "sse", Type: "sse", Sse: model.Sse{Mode: "request"}}, failure: "job sse: unsupported sse mode request"},
+		{job: model.Job{Id: "http", Type: "http", Assertions: status}},
+		{job: model.Job{Id: "request", Type: "websocket", Websocket: model.Websocket{Mode: "request", Assertions: body}}},
+		{job: model.Job{Id: "request", Type: "websocket", Websocket: model.Websocket{Mode: "request"}}, failure: "request mode requires assertions"},
+	}
+	for _, tt := range tests {
+		err := validateConfig(&model.Config{Jobs: []model.Job{tt.job}})
+		if tt.failure == "" {
+			if err != nil {
+				t.Errorf("%s: unexpected error: %v", tt.job.Id, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.failure) {
+			t.Errorf("%s: expected %q, got %v", tt.job.Id, tt.failure, err)
+		}
+	}
+}
+
+func TestValidateScenario(t *testing.T) {
+	follow := false
+	tests := []struct {
+		name    string
+		step    model.ScenarioStep
+		failure string
+	}{
+		{name: "http", step: model.ScenarioStep{Url: "http://api/login", Request: model.Request{FollowRedirects: &follow},
+			Extract: []model.Extract{{Variable: "token", Type: "jsonpath", Path: "$.token"}}}},
+		{name: "missing url", step: model.ScenarioStep{}, failure: "missing url"},
+		{name: "transport", step: model.ScenarioStep{Url: "http://api", Request: model.Request{Proxy: "http://proxy"}}, failure: "set in job request block"},
+		{name: "ws redirects", step: model.ScenarioStep{Type: "websocket", Url: "ws://api", Request: model.Request{MaxRedirects: 2}}, failure: "doesn't follow redirects"},
+		{name: "ws form", step: model.ScenarioStep{Type: "websocket", Url: "ws://api", Request: model.Request{Form: map[string]string{"a": "1"}}}, failure: "doesn't support method and form"},
+		{name: "ws method", step: model.ScenarioStep{Type: "websocket", Url: "ws://api", Request: model.Request{Method: "POST"}}, failure: "doesn't support method and form"},
+		{name: "type", step: model.ScenarioStep{Type: "ftp", Url: "ftp://api"}, failure: "unsupported step type ftp"},
+		{name: "assertion", step: model.ScenarioStep{Url: "http://api", Assertions: []model.Assertion{{Type: "xpath", Path: "//["}}}, failure: "xpath assertion //["},
+		{name: "extract", step: model.ScenarioStep{Url: "http://api", Extract: []model.Extract{{Variable: "id", Type: "regex", Pattern: "("}}}, failure: "extract id"},
+	}
+	for _, tt := range tests {
+		tt.step.Name = tt.name
+		job := model.Job{Id: "s", Type: "scenario", Scenario: model.Scenario{Steps: []model.ScenarioStep{tt.step}}}
+		err := validateConfig(&model.Config{Jobs: []model.Job{job}})
+		if tt.failure == "" {
+			if err != nil {
+				t.Errorf("%s: unexpected error: %v", tt.name, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), "step "+tt.name+": ") || !strings.Contains(err.Error(), tt.failure) {
+			t.Errorf("%s: expected %q, got %v", tt.name, tt.failure, err)
+		}
+	}
+
+	if err := validateConfig(&model.Config{Jobs: []model.Job{{Id: "s", Type: "scenario"}}}); err == nil {
+		t.Error("expected error for scenario without steps")
+	}
+}
+
+func TestValidateChecks(t *testing.T) {
+	tests := []struct {
+		job     model.Job
+		failure string
+	}{
+		{job: model.Job{Id: "http", Type: "http", Assertions: []model.Assertion{
+			{Type: "status", Codes: []string{"200", "3xx", "400-404"}},
+			{Type: "header", Name: "Content-Type", Operator: "contains", Value: "json"},
+			{Type: "jsonpath", Path: "$.items[*].id", Operator: ">=", Value: "1"},
+			{Type: "xpath", Path: "count(//node)", Value: "2"},
+			{Type: "body", Operator: "matches", Value: "${pattern}"},
+			{Type: "bodySize", Max: 1024},
+		}}},
+		{job: model.Job{Id: "http", Type: "http", Assertions: []model.Assertion{{Type: "status", Codes: []string{"2x"}}}}, failure: "status assertion: invalid code 2x"},
+		{job: model.Job{Id: "http", Type: "http_get", Assertions: []model.Assertion{{Type: "status"}}}, failure: "missing codes"},
+		{job: model.Job{Id: "http", Type: "http", Assertions: []model.Assertion{{Type: "header", Operator: "exists"}}}, failure: "missing header name"},
+		{job: model.Job{Id: "http", Type: "http", Assertions: []model.Assertion{{Type: "bodySize"}}}, failure: "max must be positive"},
+		{job: model.Job{Id: "http", Type: "http", Assertions: []model.Assertion{{Type: "jsonpath", Path: "$.a", Operator: "~"}}}, failure: "jsonpath assertion $.a: unsupported operator ~"},
+		{job: model.Job{Id: "http", Type: "http_post", Assertions: []model.Assertion{{Type: "body", Operator: "matches", Value: "("}}}, failure: "invalid regex ("},
+		{job: model.Job{Id: "http", Type: "http", Assertions: []model.Assertion{{Type: "cookie"}}}, failure: "unsupported assertion type cookie"},
+		{job: model.Job{Id: "slo", Type: "tcp", Slo: model.Slo{Target: 99.9, Window: "7d"}}},
+		{job: model.Job{Id: "slo", Type: "tcp", Slo: model.Slo{Target: 99.9, Window: "1 week"}}, failure: "invalid slo window 1 week"},
+		{job: model.Job{Id: "slo", Type: "tcp", Slo: model.Slo{Target: 99.9, Window: "1m"}}, failure: "invalid slo window 1m"},
+		{job: model.Job{Id: "tcp", Type: "tcp", Tcp: model.Tcp{Tls: "starttls", ExpectRegex: "^220 "}}},
+		{job: model.Job{Id: "tcp", Type: "tcp", Tcp: model.Tcp{Tls: "ssl"}}, failure: "unsupported tcp tls mode ssl"},
+		{job: model.Job{Id: "tcp", Type: "tcp", Tcp: model.Tcp{ExpectRegex: "("}}, failure: "invalid expect regex"},
+		{job: model.Job{Id: "dns", Type: "dns", Dns: model.Dns{Name: "api.test", RecordType: "srv", Rcode: "nxdomain", Resolver: "10.0.0.53", Protocol: "tcp"}}},
+		{job: model.Job{Id: "dns", Type: "dns", Dns: model.Dns{RecordType: "A"}}, failure: "missing dns name"},
+		{job: model.Job{Id: "dns", Type: "dns", Dns: model.Dns{Name: "api.test", RecordType: "BOGUS"}}, failure: "unsupported dns record type BOGUS"},
+		{job: model.Job{Id: "dns", Type: "dns", Dns: model.Dns{Name: "api.test", Rcode: "FAILED"}}, failure: "unsupported dns rcode FAILED"},
+		{job: model.Job{Id: "dns", Type: "dns", Dns: model.Dns{Name: "api.test", Protocol: "http"}}, failure: "unsupported dns protocol http"},
+		{job: model.Job{Id: "dns", Type: "dns", Dns: model.Dns{Name: "api.test", Resolver: "10.0.0.53:dns"}}, failure: "invalid dns resolver"},
+		{job: model.Job{Id: "tls", Type: "tls", Tls: model.Tls{MinVersion: "1.2"}}},
+		{job: model.Job{Id: "tls", Type: "http", Tls: model.Tls{Enabled: true, MinVersion: "1.4"}}, failure: "unsupported minimal tls version 1.4"},
+		{job: model.Job{Id: "grpc", Type: "grpc", Urls: []string{"api:50051"}, Grpc: model.Grpc{Tls: true, InsecureSkipVerify: true}}},
+		{job: model.Job{Id: "grpc", Type: "grpc", Grpc: model.Grpc{Service: "api"}}, failure: "missing grpc targets"},
+		{job: model.Job{Id: "grpc", Type: "grpc", Urls: []string{"api:50051"}, Grpc: model.Grpc{InsecureSkipVerify: true}}, failure: "requires tls"},
+		{job: model.Job{Id: "sql", Type: "sql", Sql: model.Sql{Driver: "postgres", ConnectionStringEnv: "DSN", Query: "select 1"}}},
+		{job: model.Job{Id: "sql", Type: "sql", Sql: model.Sql{Driver: "mysql", ConnectionString: "db", Query: "select 1"}}, failure: "unsupported sql driver mysql"},
+		{job: model.Job{Id: "sql", Type: "sql", Sql: model.Sql{Driver: "sqlite", Query: "select 1"}}, failure: "missing sql connection string"},
+		{job: model.Job{Id: "sql", Type: "sql", Sql: model.Sql{Driver: "sqlite", ConnectionString: ":memory:"}}, failure: "missing sql query"},
+		{job: model.Job{Id: "exec", Type: "exec", Exec: model.Exec{Command: "/usr/lib/nagios/plugins/check_disk"}}},
+		{job: model.Job{Id: "exec", Type: "exec"}, failure: "missing exec command"},
+		{job: model.Job{Id: "stomp", Type: "websocket", Websocket: model.Websocket{Protocol: "stomp", Stomp: model.Stomp{Destination: "/topic/ticks"}}}},
+		{job: model.Job{Id: "stomp", Type: "websocket", Websocket: model.Websocket{Protocol: "stomp"}}, failure: "missing stomp destination"},
+		{job: model.Job{Id: "mqtt", Type: "websocket", Websocket: model.Websocket{Protocol: "mqtt"}}, failure: "unsupported websocket protocol mqtt"},
+		{job: model.Job{Id: "ws-auth", Type: "websocket", Websocket: model.Websocket{Auth: "query"}}},
+		{job: model.Job{Id: "ws-auth", Type: "websocket", Websocket: model.Websocket{Auth: "cookie"}}, failure: "unsupported websocket auth cookie"},
+		{job: model.Job{Id: "h2", Type: "http", Request: model.Request{HttpVersion: "2", Proxy: "http://proxy:3128"}}, failure: "proxy is not supported with http version 2"},
+		{job: model.Job{Id: "promql", Type: "promql", Promql: model.Promql{Url: "http://prometheus:9090", Query: "up", OnEmpty: "down"}}},
+		{job: model.Job{Id: "promql", Type: "promql", Promql: model.Promql{Query: "up"}}, failure: "missing promql url or query"},
+		{job: model.Job{Id: "promql", Type: "promql", Promql: model.Promql{Url: "http://prometheus:9090"}}, failure: "missing promql url or query"},
+		{job: model.Job{Id: "promql", Type: "promql", Promql: model.Promql{Url: "http://prometheus:9090", Query: "up", OnEmpty: "degraded"}}, failure: "unsupported promql onEmpty degraded"},
+		{job: model.Job{Id: "resources", Type: "resources", Resources: model.Resources{Cpu: model.ResourceThreshold{Max: "500m"}, Memory: model.ResourceThreshold{MaxPercent: 90, Of: "requests"}}}},
+		{job: model.Job{Id: "resources", Type: "resources", Resources: model.Resources{Cpu: model.ResourceThreshold{Max: "half"}}}, failure: "invalid cpu max half"},
+		{job: model.Job{Id: "resources", Type: "resources", Resources: model.Resources{Memory: model.ResourceThreshold{MaxPercent: 90, Of: "usage"}}}, failure: "unsupported memory threshold of usage"},
+		{job: model.Job{Id: "kubernetes", Type: "kubernetes", Kubernetes: model.Kubernetes{Workloads: []model.Workload{{Kind: "Deployment", Name: "api"}}, Services: []string{"api"}}}},
+		{job: model.Job{Id: "kubernetes", Type: "kubernetes", Kubernetes: model.Kubernetes{Workloads: []model.Workload{{Kind: "replicaset", Name: "api"}}}}, failure: "unsupported workload kind replicaset"},
+		{job: model.Job{Id: "kubernetes", Type: "kubernetes", Kubernetes: model.Kubernetes{Workloads: []model.Workload{{Kind: "statefulset"}}}}, failure: "missing statefulset name"},
+		{job: model.Job{Id: "kubernetes", Type: "kubernetes", Kubernetes: model.Kubernetes{Services: []string{""}}}, failure: "missing service name"},
+		{job: model.Job{Id: "cronjob", Type: "cronjob", CronJob: model.CronJob{Name: "backup"}}},
+		{job: model.Job{Id: "cronjob", Type: "cronjob"}, failure: "missing cronjob name"},
+		{job: model.Job{Id: "logs", Type: "logs", Logs: model.Logs{Patterns: []model.LogPattern{{Name: "oom", Regex: "OutOfMemory"}}}}},
+		{job: model.Job{Id: "logs", Type: "logs"}, failure: "missing log patterns"},
+		{job: model.Job{Id: "logs", Type: "logs", Logs: model.Logs{Patterns: []model.LogPattern{{Regex: "[a-"}}}}, failure: "invalid log pattern [a-"},
+	}
+	for _, tt := range tests {
+		err := validateConfig(&model.Config{Jobs: []model.Job{tt.job}})
+		if tt.failure == "" {
+			if err != nil {
+				t.Errorf("%s: unexpected error: %v", tt.job.Id, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.failure) {
+			t.Errorf("%s: expected %q, got %v", tt.job.Id, tt.failure, err)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package healthcheck
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/healthcheck-watchdog/cmd/authentication"
+	"github.com/healthcheck-watchdog/cmd/common"
+	"github.com/healthcheck-watchdog/cmd/model"
+)
+
+// wsHandshake returns url and headers of websocket handshake with configured
+// headers and access token in header or query parameter. Token is empty without auth_enabled
+func wsHandshake(function *model.Job, u string, token string) (string, http.Header, error) {
+	config := &function.Websocket
+	vars := map[string]string{"token": token}
+
+	header := http.Header{}
+	for key, value := range config.Headers {
+		header.Set(key, expand(value, vars))
+	}
+
+	if !function.AuthEnabled {
+		return u, header, nil
+	}
+
+	switch config.Auth {
+	case common.WsAuthHeader:
+		name := config.AuthName
+		if name == "" {
+			name = common.DefaultWsAuthHeader
+		}
+		template := config.AuthTemplate
+		if template == "" {
+			template = "Bearer ${token}"
+		}
+		header.Set(name, expand(template, vars))
+	case common.WsAuthQuery:
+		parsed, err := url.Parse(u)
+		if err != nil {
+			return "", nil, err
+		}
+		name := config.AuthName
+		if name == "" {
+			name = common.DefaultWsAuthQuery
+		}
+		template := config.AuthTemplate
+		if template == "" {
+			template = "${token}"
+		}
+		query := parsed.Query()
+		query.Set(name, expand(template, vars))
+		parsed.RawQuery = query.Encode()
+		u = parsed.String()
+	case "", common.WsAuthMessage:
+	default:
+		return "", nil, fmt.Errorf("unsupported websocket auth %s", config.Auth)
+	}
+
+	return u, header, nil
+}
+
+// wsInitMessages returns messages sent after connect: access token
+// message and subscribe payload
+func wsInitMessages(function *model.Job, token string) [][]byte {
+	config := &function.Websocket
+	vars := map[string]string{"token": token}
+
+	messages := make([][]byte, 0, 2)
+	if wsMessageAuth(function) {
+		template := config.AuthTemplate
+		if template == "" {
+			template = common.DefaultWsAuthMessage
+		}
+		messages = append(messages, []byte(expand(template, vars)))
+	}
+	if config.Subscribe != "" {
+		messages = append(messages, []byte(expand(config.Subscribe, vars)))
+	}
+
+	return messages
+}
+
+// wsToken gets access token for jobs with auth_enabled. Token failures are unknown
+func wsToken(authClient *authentication.AuthClient, function *model.Job) (string, error) {
+	if !function.AuthEnabled {
+		return "", nil
+	}
+
+	token, err := authClient.GetToken()
+	if err != nil {
+		return "", unknown(fmt.Errorf("access token: %w", err))
+	}
+
+	return token.AccessToken, nil
+}
+
+// wsLabel extracts label of messages count from json message
+func wsLabel(function *model.Job, message []byte) string {
+	path := function.Websocket.LabelPath
+	if path == "" {
+		path = common.DefaultWsLabelPath
+	}
+
+	return messageLabel(path, message)
+}
+
+// wsAssert evaluates message assertions. Only matching messages keep stream healthy
+func wsAssert(function *model.Job, message []byte) []string {
+	return assertResponse(function.Websocket.Assertions, 0, nil, message)
+}
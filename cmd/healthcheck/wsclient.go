@@ -1,172 +1,311 @@
 package healthcheck
 
 import (
+	"context"
 	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
+	"github.com/gorilla/websocket"
+	"github.com/healthcheck-watchdog/cmd/authentication"
+	"github.com/healthcheck-watchdog/cmd/common"
 	"github.com/healthcheck-watchdog/cmd/exporter"
-	"github.com/sacOO7/gowebsocket"
+	"github.com/healthcheck-watchdog/cmd/model"
 	log "github.com/sirupsen/logrus"
 )
 
+// WsClient manages websocket connections of jobs. Each connection is served by
+// its own goroutine moving between connecting, connected and backoff states
 type WsClient struct {
 	mx          sync.Mutex
+	ctx         context.Context
+	cancel      context.CancelFunc
 	connections map[string]*WsConnection
 	prometheus  *exporter.Exporter
+	authClient  *authentication.AuthClient
+	// pingInterval and pongWait keep connection alive, the connection
+	// fails when nothing is received for pongWait
+	pingInterval time.Duration
+	pongWait     time.Duration
 }
 
-func (wc *WsClient) getConnection(key string) *WsConnection {
-	wc.mx.Lock()
-	defer wc.mx.Unlock()
-
-	return wc.connections[key]
+// WsConnection holds state of a single job url connection
+type WsConnection struct {
+	streamState
+	writeMx  sync.Mutex
+	conn     *websocket.Conn
+	protocol wsProtocol
+	pending  *wsPending
 }
 
-func (wc *WsClient) setConnection(key string, value *WsConnection) {
-	wc.mx.Lock()
-	defer wc.mx.Unlock()
-
-	wc.connections[key] = value
+// wsPending is request waiting for a reply matching assertions
+type wsPending struct {
+	assertions []model.Assertion
+	reply      chan struct{}
 }
 
-type WsConnection struct {
-	mx   sync.Mutex
-	urls map[string]*Url
+func NewWsClient(prometheus *exporter.Exporter, authClient *authentication.AuthClient) *WsClient {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &WsClient{
+		ctx:          ctx,
+		cancel:       cancel,
+		connections:  make(map[string]*WsConnection),
+		prometheus:   prometheus,
+		authClient:   authClient,
+		pingInterval: common.WsPingInterval * time.Second,
+		pongWait:     common.WsPongWait * time.Second,
+	}
 }
 
-type Url struct {
-	url  string
-	time int64
+// Close stops all connections
+func (wc *WsClient) Close() {
+	wc.cancel()
 }
 
-func (wc *WsConnection) getUrl(key string) *Url {
+// getConnection returns connection of the job url, starting it on first call
+func (wc *WsClient) getConnection(function *model.Job, u string) *WsConnection {
 	wc.mx.Lock()
 	defer wc.mx.Unlock()
 
-	return wc.urls[key]
-}
-
-func (wc *WsConnection) setUrl(key string, value *Url) {
-	wc.mx.Lock()
-	defer wc.mx.Unlock()
+	key := fmt.Sprintf("%s/%s", function.Id, u)
+	c, found := wc.connections[key]
+	if !found {
+		log.Info(fmt.Sprintf("%s. Registering url: %s", function.Id, u))
+		c = &WsConnection{}
+		c.init(function, u, function.Websocket.Interval, function.Websocket.Mode == common.ModeThroughput)
+		wc.connections[key] = c
+		go c.reconnect(wc.ctx, wc.prometheus, func() (bool, error) {
+			return wc.serve(c)
+		})
+	}
 
-	wc.urls[key] = value
+	return c
 }
 
-// func (wc *WsConnection) getUrlString(key string) string {
-// 	wc.mx.Lock()
-// 	defer wc.mx.Unlock()
-
-// 	return wc.urls[key].url
-// }
-
-// func (wc *WsConnection) getUrlTime(key string) int64 {
-// 	wc.mx.Lock()
-// 	defer wc.mx.Unlock()
-
-// 	return wc.urls[key].time
-// }
-
-func (wc *WsConnection) setUrlTime(key string, value int64) {
-	wc.mx.Lock()
-	defer wc.mx.Unlock()
+// serve connects and reads messages until connection fails. Frames are decoded by
+// the job protocol and its payloads are handled. Reports whether any payload was received
+func (wc *WsClient) serve(c *WsConnection) (bool, error) {
+	function := c.function
 
-	url := &Url{
-		url:  key,
-		time: value,
+	token, err := wsToken(wc.authClient, function)
+	if err != nil {
+		return false, err
+	}
+	dialUrl, header, err := wsHandshake(function, c.url, token)
+	if err != nil {
+		return false, err
+	}
+	protocol, err := newWsProtocol(function, c.url, token)
+	if err != nil {
+		return false, unknown(err)
+	}
+	if dialUrl, err = protocol.url(dialUrl); err != nil {
+		return false, err
 	}
 
-	wc.urls[key] = url
-}
+	dialer := websocket.Dialer{
+		Proxy:            http.ProxyFromEnvironment,
+		HandshakeTimeout: common.DefaultCheckTimeout * time.Second,
+		Subprotocols:     function.Websocket.Subprotocols,
+	}
+	conn, _, err := dialer.DialContext(wc.ctx, dialUrl, header)
+	if err != nil {
+		return false, fmt.Errorf("connect: %w", err)
+	}
+	defer conn.Close()
 
-func NewWsClient(prometheus *exporter.Exporter) *WsClient {
-	connection := make(map[string]*WsConnection)
-	wc := WsClient{
-		connections: connection,
-		prometheus:  prometheus,
+	for _, message := range protocol.open() {
+		if err := conn.WriteMessage(websocket.TextMessage, message); err != nil {
+			return false, fmt.Errorf("write: %w", err)
+		}
 	}
 
-	return &wc
-}
+	c.mx.Lock()
+	c.conn = conn
+	c.protocol = protocol
+	c.mx.Unlock()
+	defer func() {
+		c.mx.Lock()
+		c.conn = nil
+		c.protocol = nil
+		c.mx.Unlock()
+	}()
+
+	c.setState(wc.prometheus, common.StreamConnected, nil)
+	log.Info(fmt.Sprintf("%s. Connected ws (%s)", function.Id, c.url))
+
+	_ = conn.SetReadDeadline(time.Now().Add(wc.pongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(wc.pongWait))
+	})
+
+	done := make(chan struct{})
+	defer close(done)
+	stale := make(chan error, 1)
+	go wc.keepAlive(c, conn, protocol, done, stale)
+
+	received := false
+	for {
+		_, message, err := conn.ReadMessage()
+		if err != nil {
+			select {
+			case staleErr := <-stale:
+				return received, staleErr
+			default:
+				return received, fmt.Errorf("read: %w", err)
+			}
+		}
+		_ = conn.SetReadDeadline(time.Now().Add(wc.pongWait))
 
-func (ws *WsClient) getWsConnection(jobId string) *WsConnection {
-	connection := ws.getConnection(jobId)
-	if connection == nil {
-		connection = &WsConnection{
-			urls: make(map[string]*Url),
+		payloads, replies, err := protocol.decode(message)
+		for _, reply := range replies {
+			if err := c.write(conn, reply); err != nil {
+				return received, fmt.Errorf("write: %w", err)
+			}
+		}
+		// payloads of frames before a protocol error are handled
+		for _, payload := range payloads {
+			received = true
+			wc.handle(c, payload)
+		}
+		if err != nil {
+			return received, err
 		}
-		ws.setConnection(jobId, connection)
 	}
-
-	return connection
 }
 
-func (ws *WsClient) getWsUrl(jobId string, urlAddress string) *Url {
-	connection := ws.getWsConnection(jobId)
-	url := connection.getUrl(urlAddress)
-	if url == nil {
-		url = &Url{
-			url:  urlAddress,
-			time: time.Now().Unix(),
+// keepAlive pings the server, sends protocol heartbeats and closes connection
+// on client close or when no message is received for ResponseTimeout
+func (wc *WsClient) keepAlive(c *WsConnection, conn *websocket.Conn, protocol wsProtocol, done chan struct{}, stale chan error) {
+	ticker := time.NewTicker(wc.pingInterval)
+	defer ticker.Stop()
+
+	staleTicker := time.NewTicker(time.Second)
+	defer staleTicker.Stop()
+
+	// idle time is counted from connect, so reconnect is not stale immediately
+	connected := time.Now()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-wc.ctx.Done():
+			_ = conn.WriteControl(websocket.CloseMessage,
+				websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), time.Now().Add(time.Second))
+			_ = conn.Close()
+			return
+		case <-ticker.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(time.Second)); err != nil {
+				log.Warn(fmt.Sprintf("%s. Failed to ping ws (%s): %s", c.function.Id, c.url, err.Error()))
+			}
+			if heartbeat := protocol.heartbeat(); heartbeat != nil {
+				if err := c.write(conn, heartbeat); err != nil {
+					log.Warn(fmt.Sprintf("%s. Failed to send heartbeat ws (%s): %s", c.function.Id, c.url, err.Error()))
+				}
+			}
+		case <-staleTicker.C:
+			timeout := time.Duration(c.function.ResponseTimeout) * time.Second
+			if timeout > 0 && c.idle(connected) > timeout {
+				stale <- fmt.Errorf("no messages for %s", timeout)
+				_ = conn.Close()
+				return
+			}
 		}
-		connection.setUrl(urlAddress, url)
-		ws.addWsUrl(url.url, connection, jobId)
 	}
-
-	return url
 }
 
-func (ws *WsClient) deleteWsUrl(jobId string, urlAddress string) {
-	connection := ws.getWsConnection(jobId)
-	if connection.urls[urlAddress] != nil {
-		delete(connection.urls, urlAddress)
-	}
+// write sends text frame, writes of reader, keepalive and requests are serialized
+func (c *WsConnection) write(conn *websocket.Conn, message []byte) error {
+	c.writeMx.Lock()
+	defer c.writeMx.Unlock()
+
+	return conn.WriteMessage(websocket.TextMessage, message)
 }
 
-func (wsClient *WsClient) addWsUrl(url string, connection *WsConnection, jobId string) {
-	log.Info(fmt.Sprintf("Registering url: %s", url))
-	socket := gowebsocket.New(url)
+func (wc *WsClient) handle(c *WsConnection, message []byte) {
+	function := c.function
+	log.Debug(fmt.Sprintf("%s. Received message: %s", function.Id, message))
 
-	socket.OnConnectError = func(err error, socket gowebsocket.Socket) {
-		socket.Close()
-		wsClient.deleteWsUrl(jobId, url)
-		//wsClient.getWsUrl(jobId, url)
-		log.Error("Received connect error - ", err)
-	}
+	wc.prometheus.IncCounter(function.Id, wsLabel(function, message))
 
-	socket.OnConnected = func(socket gowebsocket.Socket) {
-		log.Info("Connected to server")
+	// in request mode assertions select the reply, any message keeps connection fresh
+	if function.Websocket.Mode == common.ModeRequest {
+		c.mx.Lock()
+		if c.pending != nil && len(assertResponse(c.pending.assertions, 0, nil, message)) == 0 {
+			select {
+			case c.pending.reply <- struct{}{}:
+			default:
+			}
+		}
+		c.mx.Unlock()
+	} else if failures := wsAssert(function, message); len(failures) > 0 {
+		log.Warn(fmt.Sprintf("%s. Message on %s does not match assertions: %s", function.Id, c.url, strings.Join(failures, "; ")))
+		return
 	}
 
-	socket.OnTextMessage = func(message string, socket gowebsocket.Socket) {
-		log.Info(jobId + ": Received message - " + message)
-		wsClient.prometheus.IncCounter(jobId, "")
-		connection.setUrlTime(url, time.Now().Unix())
-	}
+	c.record(wc.prometheus)
+}
 
-	socket.OnPingReceived = func(data string, socket gowebsocket.Socket) {
-		log.Info("Received ping - " + data)
-	}
+// request sends message and waits for the first reply matching assertions.
+// ${requestId} in message and assertion values is replaced with unique id of the request
+func (wc *WsClient) request(c *WsConnection, timeout time.Duration) (time.Duration, error) {
+	function := c.function
+	vars := map[string]string{"requestId": strconv.FormatInt(time.Now().UnixNano(), 36)}
 
-	socket.OnPongReceived = func(data string, socket gowebsocket.Socket) {
-		log.Info("Received pong - " + data)
+	assertions := make([]model.Assertion, len(function.Websocket.Assertions))
+	for i, a := range function.Websocket.Assertions {
+		a.Value = expand(a.Value, vars)
+		assertions[i] = a
 	}
-
-	socket.OnDisconnected = func(err error, socket gowebsocket.Socket) {
-		log.Info("Disconnected from server ")
-		socket.Close()
-		wsClient.deleteWsUrl(jobId, url)
-		//wsClient.getWsUrl(jobId, url)
+	pending := &wsPending{
+		assertions: assertions,
+		reply:      make(chan struct{}, 1),
 	}
 
-	socket.Connect()
-}
+	// wait for connection established on the first check or after reconnect
+	deadline := time.Now().Add(timeout)
+	var conn *websocket.Conn
+	var protocol wsProtocol
+	for {
+		c.mx.Lock()
+		conn, protocol = c.conn, c.protocol
+		if conn != nil {
+			c.pending = pending
+		}
+		c.mx.Unlock()
 
-func (ws *WsClient) TimeLastMessage(jobId string, url string) int64 {
-	return ws.getWsUrl(jobId, url).time
-}
+		if conn != nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			state, err := c.status()
+			if err != nil {
+				return 0, connectionFailure(err, fmt.Errorf("connection %s: %s", state, err.Error()))
+			}
+			return 0, fmt.Errorf("connection %s", state)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	defer func() {
+		c.mx.Lock()
+		c.pending = nil
+		c.mx.Unlock()
+	}()
+
+	start := time.Now()
+	if err := c.write(conn, protocol.encode([]byte(expand(function.Websocket.Message, vars)))); err != nil {
+		return 0, fmt.Errorf("write: %s", err.Error())
+	}
 
-func (ws *WsClient) TimeDifferenceWithLastMessage(jobId string, url string) int64 {
-	return time.Now().Unix() - ws.getWsUrl(jobId, url).time
+	select {
+	case <-pending.reply:
+		return time.Since(start), nil
+	case <-time.After(time.Until(deadline)):
+		return 0, fmt.Errorf("no reply in %s", timeout)
+	}
 }
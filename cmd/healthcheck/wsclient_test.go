@@ -0,0 +1,250 @@
+package healthcheck
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/healthcheck-watchdog/cmd/common"
+	"github.com/healthcheck-watchdog/cmd/exporter"
+	"github.com/healthcheck-watchdog/cmd/model"
+)
+
+// wsServer starts websocket server serving each connection with handler and
+// returns its ws url
+func wsServer(t *testing.T, handler func(n int, conn *websocket.Conn)) string {
+	t.Helper()
+	upgrader := websocket.Upgrader{}
+	var mx sync.Mutex
+	connections := 0
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mx.Lock()
+		connections++
+		n := connections
+		mx.Unlock()
+
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		handler(n, conn)
+	}))
+	t.Cleanup(server.Close)
+
+	return "ws" + strings.TrimPrefix(server.URL, "http")
+}
+
+// waitFor polls condition until it holds or timeout expires
+func waitFor(timeout time.Duration, condition func() bool) bool {
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		if condition() {
+			return true
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	return condition()
+}
+
+func TestNextBackoff(t *testing.T) {
+	tests := []struct {
+		backoff  time.Duration
+		min      time.Duration
+		expected time.Duration
+	}{
+		{backoff: time.Second, min: time.Second, expected: 2 * time.Second},
+		{backoff: 16 * time.Second, min: time.Second, expected: 32 * time.Second},
+		{backoff: 32 * time.Second, min: time.Second, expected: 60 * time.Second},
+		{backoff: 60 * time.Second, min: time.Second, expected: 60 * time.Second},
+		{backoff: 250 * time.Millisecond, min: 250 * time.Millisecond, expected: 500 * time.Millisecond},
+		// reconnect delay requested by server above the maximum is kept
+		{backoff: 90 * time.Second, min: 90 * time.Second, expected: 90 * time.Second},
+	}
+	for _, tt := range tests {
+		if backoff := nextBackoff(tt.backoff, tt.min); backoff != tt.expected {
+			t.Errorf("%s (min %s): expected %s, got %s", tt.backoff, tt.min, tt.expected, backoff)
+		}
+	}
+}
+
+func TestWsReconnectBackoff(t *testing.T) {
+	var mx sync.Mutex
+	var connected []time.Time
+	u := wsServer(t, func(n int, conn *websocket.Conn) {
+		mx.Lock()
+		connected = append(connected, time.Now())
+		mx.Unlock()
+
+		// the first connection receives a message, the second one fails without messages
+		if n == 1 {
+			conn.WriteMessage(websocket.TextMessage, []byte(`{"v":1}`)) //nolint:errcheck // test server
+		}
+	})
+
+	wc := NewWsClient(&exporter.Exporter{}, nil)
+	defer wc.Close()
+	c := wc.getConnection(&model.Job{Id: "ws-backoff", Type: "websocket"}, u)
+
+	if !waitFor(10*time.Second, func() bool {
+		mx.Lock()
+		defer mx.Unlock()
+		return len(connected) >= 3
+	}) {
+		t.Fatal("expected 3 connections in 10s")
+	}
+
+	mx.Lock()
+	defer mx.Unlock()
+	// backoff is reset to 1s after a connection with messages and doubled after a failed one
+	if gap := connected[1].Sub(connected[0]); gap < time.Second || gap >= 2*time.Second {
+		t.Errorf("expected reconnect in 1s, got %s", gap)
+	}
+	if gap := connected[2].Sub(connected[1]); gap < 2*time.Second || gap >= 3*time.Second {
+		t.Errorf("expected reconnect in 2s, got %s", gap)
+	}
+	if c.messageAge() > 5*time.Second {
+		t.Errorf("expected message recorded, got age %s", c.messageAge())
+	}
+}
+
+func TestWsPongWait(t *testing.T) {
+	tests := []struct {
+		name    string
+		read    bool
+		state   string
+		failure string
+	}{
+		// server reading messages answers pings with pongs
+		{name: "pong", read: true, state: common.StreamConnected},
+		{name: "no pong", read: false, state: common.StreamBackoff, failure: "i/o timeout"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u := wsServer(t, func(n int, conn *websocket.Conn) {
+				if tt.read {
+					for {
+						if _, _, err := conn.ReadMessage(); err != nil {
+							return
+						}
+					}
+				}
+				time.Sleep(2 * time.Second)
+			})
+
+			wc := NewWsClient(&exporter.Exporter{}, nil)
+			wc.pingInterval = 100 * time.Millisecond
+			wc.pongWait = 300 * time.Millisecond
+			defer wc.Close()
+			c := wc.getConnection(&model.Job{Id: "ws-pong-" + tt.name, Type: "websocket"}, u)
+
+			time.Sleep(time.Second)
+			state, err := c.status()
+			if state != tt.state {
+				t.Errorf("expected %s, got %s %v", tt.state, state, err)
+			}
+			if tt.failure != "" && (err == nil || !strings.Contains(err.Error(), tt.failure)) {
+				t.Errorf("expected %q, got %v", tt.failure, err)
+			}
+		})
+	}
+}
+
+func TestWsRequest(t *testing.T) {
+	u := wsServer(t, func(n int, conn *websocket.Conn) {
+		for {
+			_, message, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			var request struct {
+				Id    string `json:"id"`
+				Reply bool   `json:"reply"`
+			}
+			if json.Unmarshal(message, &request) != nil || !request.Reply {
+				continue
+			}
+			// reply of another request is not accepted
+			conn.WriteMessage(websocket.TextMessage, []byte(`{"id":"other"}`)) //nolint:errcheck // test server
+			time.Sleep(50 * time.Millisecond)
+			conn.WriteMessage(websocket.TextMessage, []byte(`{"id":"`+request.Id+`"}`)) //nolint:errcheck // test server
+		}
+	})
+
+	tests := []struct {
+		name    string
+		message string
+		failure string
+	}{
+		{name: "reply", message: `{"id":"${requestId}","reply":true}`},
+		{name: "no reply", message: `{"id":"${requestId}","reply":false}`, failure: "wss request on url " + u + ": no reply in 1s"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hc := &HealthCheck{exporter: &exporter.Exporter{}, wsClient: NewWsClient(&exporter.Exporter{}, nil)}
+			defer hc.wsClient.Close()
+
+			function := &model.Job{Id: "ws-request-" + tt.name, Type: "websocket", Urls: []string{u}, Websocket: model.Websocket{
+				Mode: common.ModeRequest, Message: tt.message, ReplyTimeout: 1,
+				Assertions: []model.Assertion{{Type: "jsonpath", Path: "$.id", Value: "${requestId}"}},
+			}}
+			latency, err := hc.checkWs(function)
+			if tt.failure != "" {
+				if err == nil || err.Error() != tt.failure {
+					t.Errorf("expected %q, got %v", tt.failure, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if latency < 50*time.Millisecond || latency > time.Second {
+				t.Errorf("expected reply time of about 50ms, got %s", latency)
+			}
+		})
+	}
+}
+
+func TestWsThroughput(t *testing.T) {
+	u := wsServer(t, func(n int, conn *websocket.Conn) {
+		ticker := time.NewTicker(50 * time.Millisecond)
+		defer ticker.Stop()
+		for range ticker.C {
+			if err := conn.WriteMessage(websocket.TextMessage, []byte(`{"v":1}`)); err != nil {
+				return
+			}
+		}
+	})
+
+	hc := &HealthCheck{exporter: &exporter.Exporter{}, wsClient: NewWsClient(&exporter.Exporter{}, nil)}
+	defer hc.wsClient.Close()
+
+	function := &model.Job{Id: "ws-throughput", Type: "websocket", Urls: []string{u}, Websocket: model.Websocket{
+		Mode: common.ModeThroughput, Interval: 1, MinMessages: 5,
+	}}
+	// messages are collected for the whole interval before the rate is evaluated
+	if _, err := hc.checkWs(function); !isUnknown(err) {
+		t.Errorf("expected unknown while collecting messages, got %v", err)
+	}
+
+	time.Sleep(1200 * time.Millisecond)
+	if _, err := hc.checkWs(function); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	c := hc.wsClient.getConnection(function, u)
+	if count, ready := c.throughput(); !ready || count < 10 || count > 30 {
+		t.Errorf("expected about 20 messages per second, got %d ready %t", count, ready)
+	}
+
+	function.Websocket.MinMessages = 100
+	if _, err := hc.checkWs(function); err == nil || isUnknown(err) || !strings.Contains(err.Error(), "expected at least 100") {
+		t.Errorf("expected too few messages, got %v", err)
+	}
+}
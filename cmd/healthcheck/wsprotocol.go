@@ -0,0 +1,68 @@
+package healthcheck
+
+import (
+	"fmt"
+
+	"github.com/healthcheck-watchdog/cmd/common"
+	"github.com/healthcheck-watchdog/cmd/model"
+)
+
+// wsProtocol frames application messages over a websocket connection.
+// A new instance is created for every connection, so it may keep session state
+type wsProtocol interface {
+	// url returns handshake url of the protocol
+	url(u string) (string, error)
+	// open returns frames sent right after connect
+	open() [][]byte
+	// decode returns payloads carried by the frame and frames to send in reply
+	decode(frame []byte) (payloads [][]byte, replies [][]byte, err error)
+	// heartbeat returns frame sent with every ping, nil when not needed
+	heartbeat() []byte
+	// encode wraps request mode message
+	encode(message []byte) []byte
+}
+
+func newWsProtocol(function *model.Job, u string, token string) (wsProtocol, error) {
+	switch function.Websocket.Protocol {
+	case "", common.WsProtocolRaw:
+		return &rawProtocol{function: function, token: token}, nil
+	case common.WsProtocolStomp:
+		return newStompProtocol(function, u, token)
+	case common.WsProtocolSocketIo:
+		return newSocketIoProtocol(function, token), nil
+	default:
+		return nil, fmt.Errorf("unsupported websocket protocol %s", function.Websocket.Protocol)
+	}
+}
+
+// rawProtocol passes websocket frames as is
+type rawProtocol struct {
+	function *model.Job
+	token    string
+}
+
+func (p *rawProtocol) url(u string) (string, error) {
+	return u, nil
+}
+
+func (p *rawProtocol) open() [][]byte {
+	return wsInitMessages(p.function, p.token)
+}
+
+func (p *rawProtocol) decode(frame []byte) ([][]byte, [][]byte, error) {
+	return [][]byte{frame}, nil, nil
+}
+
+func (p *rawProtocol) heartbeat() []byte {
+	return nil
+}
+
+func (p *rawProtocol) encode(message []byte) []byte {
+	return message
+}
+
+// wsMessageAuth reports whether access token is sent in protocol messages
+func wsMessageAuth(function *model.Job) bool {
+	auth := function.Websocket.Auth
+	return function.AuthEnabled && (auth == "" || auth == common.WsAuthMessage)
+}
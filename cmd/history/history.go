@@ -0,0 +1,94 @@
+package history
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/healthcheck-watchdog/cmd/common"
+	"github.com/healthcheck-watchdog/cmd/model"
+	log "github.com/sirupsen/logrus"
+)
+
+type History struct {
+	mx      sync.Mutex
+	size    int
+	buffers map[string]*buffer
+}
+
+// bounded ring buffer of check results
+type buffer struct {
+	results []model.CheckResult
+	next    int
+	full    bool
+}
+
+func NewHistory(config *model.Config) *History {
+	size := config.History.Size
+	if size <= 0 {
+		size = common.DefaultHistorySize
+	}
+
+	h := History{
+		size:    size,
+		buffers: make(map[string]*buffer),
+	}
+
+	log.Info(fmt.Sprintf("History initialized with size %d", size))
+
+	return &h
+}
+
+func (h *History) getBuffer(id string) *buffer {
+	b, found := h.buffers[id]
+	if !found {
+		b = &buffer{
+			results: make([]model.CheckResult, h.size),
+		}
+		h.buffers[id] = b
+	}
+
+	return b
+}
+
+func (h *History) Add(id string, result model.CheckResult) {
+	h.mx.Lock()
+	defer h.mx.Unlock()
+
+	b := h.getBuffer(id)
+	b.results[b.next] = result
+	b.next = (b.next + 1) % len(b.results)
+	if b.next == 0 {
+		b.full = true
+	}
+}
+
+// Get returns results not older than since (unix seconds) in chronological order.
+// When limit is positive only the latest limit results are returned.
+func (h *History) Get(id string, since int64, limit int) []model.CheckResult {
+	h.mx.Lock()
+	defer h.mx.Unlock()
+
+	b, found := h.buffers[id]
+	if !found {
+		return []model.CheckResult{}
+	}
+
+	start, count := 0, b.next
+	if b.full {
+		start, count = b.next, len(b.results)
+	}
+
+	result := make([]model.CheckResult, 0, count)
+	for i := 0; i < count; i++ {
+		r := b.results[(start+i)%len(b.results)]
+		if r.Timestamp >= since {
+			result = append(result, r)
+		}
+	}
+
+	if limit > 0 && len(result) > limit {
+		result = result[len(result)-limit:]
+	}
+
+	return result
+}
@@ -0,0 +1,63 @@
+package history
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/healthcheck-watchdog/cmd/model"
+)
+
+func timestamps(results []model.CheckResult) []int64 {
+	values := make([]int64, 0, len(results))
+	for _, r := range results {
+		values = append(values, r.Timestamp)
+	}
+
+	return values
+}
+
+func TestGet(t *testing.T) {
+	tests := []struct {
+		name     string
+		added    int64
+		since    int64
+		limit    int
+		expected []int64
+	}{
+		{name: "empty", added: 0, expected: []int64{}},
+		{name: "partial", added: 3, expected: []int64{1, 2, 3}},
+		{name: "full", added: 4, expected: []int64{1, 2, 3, 4}},
+		{name: "wraparound", added: 6, expected: []int64{3, 4, 5, 6}},
+		{name: "wraparound twice", added: 9, expected: []int64{6, 7, 8, 9}},
+		{name: "since", added: 6, since: 5, expected: []int64{5, 6}},
+		{name: "since all", added: 6, since: 1, expected: []int64{3, 4, 5, 6}},
+		{name: "since none", added: 6, since: 7, expected: []int64{}},
+		{name: "limit", added: 6, limit: 3, expected: []int64{4, 5, 6}},
+		{name: "limit above count", added: 2, limit: 3, expected: []int64{1, 2}},
+		{name: "since and limit", added: 6, since: 4, limit: 2, expected: []int64{5, 6}},
+	}
+	for _, tt := range tests {
+		h := NewHistory(&model.Config{History: model.History{Size: 4}})
+		h.Add("other", model.CheckResult{Timestamp: 100})
+		for i := int64(1); i <= tt.added; i++ {
+			h.Add("api", model.CheckResult{Timestamp: i})
+		}
+
+		if result := timestamps(h.Get("api", tt.since, tt.limit)); !reflect.DeepEqual(result, tt.expected) {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.expected, result)
+		}
+	}
+}
+
+func TestGetUnknownJob(t *testing.T) {
+	h := NewHistory(&model.Config{})
+	if result := h.Get("api", 0, 0); result == nil || len(result) != 0 {
+		t.Errorf("expected empty history, got %v", result)
+	}
+	if len(h.buffers) != 0 {
+		t.Error("expected no buffer allocated on read")
+	}
+	if h.size <= 0 {
+		t.Errorf("expected default size, got %d", h.size)
+	}
+}
@@ -1,8 +1,13 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"github.com/healthcheck-watchdog/cmd/api"
@@ -11,6 +16,9 @@ import (
 	"github.com/healthcheck-watchdog/cmd/configuration"
 	"github.com/healthcheck-watchdog/cmd/exporter"
 	"github.com/healthcheck-watchdog/cmd/healthcheck"
+	"github.com/healthcheck-watchdog/cmd/history"
+	"github.com/healthcheck-watchdog/cmd/slo"
+	"github.com/healthcheck-watchdog/cmd/store"
 	"github.com/healthcheck-watchdog/cmd/watchdog"
 	"github.com/rs/cors"
 	log "github.com/sirupsen/logrus"
@@ -26,14 +34,31 @@ func main() {
 	// initialize metrics exporter. panic on error
 	exporter := exporter.NewExporter(config)
 
+	// initialize state store. panic on error
+	store, err := store.NewStore(config)
+	if err != nil {
+		log.Error(fmt.Sprintf("Failed to initialize state store: %s", err.Error()))
+		panic(err)
+	}
+	defer store.Close()
+
 	// initialize cluster client. nil on empty config, panic on error
-	cluster := cluster.NewCluster(config)
+	cluster := cluster.NewCluster(config, store)
 
 	// initialize watchdog functions. panic if error
-	watchdog := watchdog.NewWatchDog(cluster, config)
+	watchdog := watchdog.NewWatchDog(cluster, config, store)
+
+	// initialize check results history
+	history := history.NewHistory(config)
+
+	// initialize availability tracker
+	slo := slo.NewTracker(config, store)
+	defer slo.Close()
 
 	// initialize healthcheck. panic if error
-	healthcheck := healthcheck.NewHealthCheck(config, authClient, exporter, watchdog, nil)
+	healthcheck := healthcheck.NewHealthCheck(config, authClient, exporter, watchdog, cluster, history, store, slo)
+	// deferred last, so checks are stopped before slo and store are closed
+	defer healthcheck.Close()
 
 	// initialize api router
 	router := api.NewRouter(healthcheck)
@@ -52,8 +77,19 @@ func main() {
 		ReadTimeout:  5 * time.Second,
 		WriteTimeout: 5 * time.Second,
 	}
+	// stop server on termination, so deferred state is saved before exit
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		log.Info("Shutting down HTTP server")
+		if err := server.Shutdown(context.Background()); err != nil {
+			log.Error(fmt.Sprintf("HTTP server shutdown error: %s", err.Error()))
+		}
+	}()
+
 	log.Info(fmt.Sprintf("HTTP server started on http://localhost%s", server.Addr))
-	if err := server.ListenAndServe(); err != nil {
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
 		log.Error(fmt.Sprintf("HTTP server error: %s", err.Error()))
 		panic(err)
 	}
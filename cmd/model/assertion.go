@@ -0,0 +1,18 @@
+package model
+
+type Assertion struct {
+	// required: true
+	Type string `json:"type,omitempty"`
+	// required: false
+	Codes []string `json:"codes,omitempty"`
+	// required: false
+	Name string `json:"name,omitempty"`
+	// required: false
+	Path string `json:"path,omitempty"`
+	// required: false
+	Operator string `json:"operator,omitempty"`
+	// required: false
+	Value string `json:"value,omitempty"`
+	// required: false
+	Max int64 `json:"max,omitempty"`
+}
@@ -0,0 +1,19 @@
+package model
+
+//swagger:model
+type CheckResult struct {
+	// required: true
+	Timestamp int64 `json:"timestamp,omitempty"`
+	// required: true
+	Status string `json:"status,omitempty"`
+	// required: true
+	Latency int64 `json:"latency,omitempty"`
+	// required: true
+	Reason string `json:"reason,omitempty"`
+	// required: false
+	Output string `json:"output,omitempty"`
+	// required: false
+	Step string `json:"step,omitempty"`
+	// required: false
+	Failures []string `json:"failures,omitempty"`
+}
@@ -8,6 +8,10 @@ type Config struct {
 	Jobs []Job `json:"jobs,omitempty"`
 
 	WatchDog WatchDog `json:"watchdog,omitempty"`
+
+	History History `json:"history,omitempty"`
+
+	Store Store `json:"store,omitempty"`
 }
 
 type Authentication struct {
@@ -0,0 +1,14 @@
+package model
+
+type CronJob struct {
+	// required: true
+	Name string `json:"name,omitempty"`
+	// required: false
+	MaxAge int `json:"maxAge,omitempty"`
+	// required: false
+	History int `json:"history,omitempty"`
+	// required: false
+	MaxFailed int `json:"maxFailed,omitempty"`
+	// required: false
+	ActiveDeadline int `json:"activeDeadline,omitempty"`
+}
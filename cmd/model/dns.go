@@ -0,0 +1,18 @@
+package model
+
+type Dns struct {
+	// required: true
+	Name string `json:"name,omitempty"`
+	// required: true
+	RecordType string `json:"recordType,omitempty"`
+	// required: false
+	Resolver string `json:"resolver,omitempty"`
+	// required: false
+	Protocol string `json:"protocol,omitempty"`
+	// required: false
+	Expected []string `json:"expected,omitempty"`
+	// required: false
+	MinRecords int `json:"minRecords,omitempty"`
+	// required: false
+	Rcode string `json:"rcode,omitempty"`
+}
@@ -0,0 +1,14 @@
+package model
+
+type Exec struct {
+	// required: true
+	Command string `json:"command,omitempty"`
+	// required: false
+	Args []string `json:"args,omitempty"`
+	// required: false
+	Env map[string]string `json:"env,omitempty"`
+	// required: false
+	Dir string `json:"dir,omitempty"`
+	// required: false
+	OutputLimit int `json:"outputLimit,omitempty"`
+}
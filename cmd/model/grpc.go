@@ -0,0 +1,14 @@
+package model
+
+type Grpc struct {
+	// required: false
+	Service string `json:"service,omitempty"`
+	// required: false
+	Tls bool `json:"tls,omitempty"`
+	// required: false
+	InsecureSkipVerify bool `json:"insecureSkipVerify,omitempty"`
+	// required: false
+	Metadata map[string]string `json:"metadata,omitempty"`
+	// required: false
+	Watch bool `json:"watch,omitempty"`
+}
@@ -0,0 +1,8 @@
+package model
+
+type History struct {
+	// required: true
+	Size int `json:"size,omitempty"`
+	// required: true
+	Summary int `json:"summary,omitempty"`
+}
@@ -24,9 +24,49 @@ type Job struct {
 	// required: true
 	ResponseTimeout int `json:"responseTimeout,omitempty"`
 	// required: true
+	DegradedAbove int64 `json:"degradedAbove,omitempty"`
+	// required: true
+	DownAbove int64 `json:"downAbove,omitempty"`
+	// required: true
 	DependentJob string `json:"dependentJob,omitempty"`
 	// required: true
 	Location Location `json:"location,omitempty"`
 	// required: true
 	WatchDogAction WatchDogAction `json:"watchdog_action,omitempty"`
+	// required: true
+	Slo Slo `json:"slo,omitempty"`
+	// required: false
+	Tcp Tcp `json:"tcp,omitempty"`
+	// required: false
+	Dns Dns `json:"dns,omitempty"`
+	// required: false
+	Tls Tls `json:"tls,omitempty"`
+	// required: false
+	Grpc Grpc `json:"grpc,omitempty"`
+	// required: false
+	Redis Redis `json:"redis,omitempty"`
+	// required: false
+	Sql Sql `json:"sql,omitempty"`
+	// required: false
+	Exec Exec `json:"exec,omitempty"`
+	// required: false
+	Websocket Websocket `json:"websocket,omitempty"`
+	// required: false
+	Sse Sse `json:"sse,omitempty"`
+	// required: false
+	Request Request `json:"request,omitempty"`
+	// required: false
+	Assertions []Assertion `json:"assertions,omitempty"`
+	// required: false
+	Scenario Scenario `json:"scenario,omitempty"`
+	// required: false
+	Resources Resources `json:"resources,omitempty"`
+	// required: false
+	Kubernetes Kubernetes `json:"kubernetes,omitempty"`
+	// required: false
+	CronJob CronJob `json:"cronJob,omitempty"`
+	// required: false
+	Logs Logs `json:"logs,omitempty"`
+	// required: false
+	Promql Promql `json:"promql,omitempty"`
 }
@@ -0,0 +1,23 @@
+package model
+
+type Kubernetes struct {
+	// required: false
+	Workloads []Workload `json:"workloads,omitempty"`
+	// required: false
+	Selector string `json:"selector,omitempty"`
+	// required: false
+	Services []string `json:"services,omitempty"`
+	// required: false
+	MaxRestarts int32 `json:"maxRestarts,omitempty"`
+	// required: false
+	RestartWindow int `json:"restartWindow,omitempty"`
+	// required: false
+	PendingTimeout int `json:"pendingTimeout,omitempty"`
+}
+
+type Workload struct {
+	// required: true
+	Kind string `json:"kind,omitempty"`
+	// required: true
+	Name string `json:"name,omitempty"`
+}
@@ -0,0 +1,23 @@
+package model
+
+type Logs struct {
+	// required: false
+	Selector string `json:"selector,omitempty"`
+	// required: false
+	Container string `json:"container,omitempty"`
+	// required: false
+	Window int `json:"window,omitempty"`
+	// required: true
+	Patterns []LogPattern `json:"patterns,omitempty"`
+	// required: false
+	Samples int `json:"samples,omitempty"`
+}
+
+type LogPattern struct {
+	// required: false
+	Name string `json:"name,omitempty"`
+	// required: true
+	Regex string `json:"regex,omitempty"`
+	// required: false
+	Max int `json:"max,omitempty"`
+}
@@ -0,0 +1,22 @@
+package model
+
+type Promql struct {
+	// required: true
+	Url string `json:"url,omitempty"`
+	// required: true
+	Query string `json:"query,omitempty"`
+	// required: false
+	Username string `json:"username,omitempty"`
+	// required: false
+	Password string `json:"password,omitempty"`
+	// required: false
+	Min *float64 `json:"min,omitempty"`
+	// required: false
+	Max *float64 `json:"max,omitempty"`
+	// required: false
+	DegradedMin *float64 `json:"degradedMin,omitempty"`
+	// required: false
+	DegradedMax *float64 `json:"degradedMax,omitempty"`
+	// required: false
+	OnEmpty string `json:"onEmpty,omitempty"`
+}
@@ -0,0 +1,51 @@
+package model
+
+type Redis struct {
+	// required: false
+	Mode string `json:"mode,omitempty"`
+	// required: false
+	Password string `json:"password,omitempty"`
+	// required: false
+	Db int `json:"db,omitempty"`
+	// required: false
+	Tls bool `json:"tls,omitempty"`
+	// required: false
+	InsecureSkipVerify bool `json:"insecureSkipVerify,omitempty"`
+	// required: false
+	SentinelMaster string `json:"sentinelMaster,omitempty"`
+	// required: false
+	SentinelPassword string `json:"sentinelPassword,omitempty"`
+	// required: false
+	Info []RedisInfo `json:"info,omitempty"`
+	// required: false
+	Queues []RedisQueue `json:"queues,omitempty"`
+	// required: false
+	Keys []RedisKey `json:"keys,omitempty"`
+}
+
+type RedisInfo struct {
+	// required: true
+	Field string `json:"field,omitempty"`
+	// required: false
+	Min *float64 `json:"min,omitempty"`
+	// required: false
+	Max *float64 `json:"max,omitempty"`
+	// required: false
+	Equals string `json:"equals,omitempty"`
+}
+
+type RedisQueue struct {
+	// required: true
+	Key string `json:"key,omitempty"`
+	// required: false
+	Type string `json:"type,omitempty"`
+	// required: true
+	MaxLength int64 `json:"maxLength,omitempty"`
+}
+
+type RedisKey struct {
+	// required: true
+	Key string `json:"key,omitempty"`
+	// required: true
+	MaxAge int64 `json:"maxAge,omitempty"`
+}
@@ -0,0 +1,32 @@
+package model
+
+type Request struct {
+	// required: false
+	Method string `json:"method,omitempty"`
+	// required: false
+	Headers map[string]string `json:"headers,omitempty"`
+	// required: false
+	Query map[string]string `json:"query,omitempty"`
+	// required: false
+	Body string `json:"body,omitempty"`
+	// required: false
+	BodyFile string `json:"bodyFile,omitempty"`
+	// required: false
+	Form map[string]string `json:"form,omitempty"`
+	// required: false
+	FollowRedirects *bool `json:"followRedirects,omitempty"`
+	// required: false
+	MaxRedirects int `json:"maxRedirects,omitempty"`
+	// required: false
+	CaFile string `json:"caFile,omitempty"`
+	// required: false
+	InsecureSkipVerify bool `json:"insecureSkipVerify,omitempty"`
+	// required: false
+	ServerName string `json:"serverName,omitempty"`
+	// required: false
+	DisableKeepAlives bool `json:"disableKeepAlives,omitempty"`
+	// required: false
+	Proxy string `json:"proxy,omitempty"`
+	// required: false
+	HttpVersion string `json:"httpVersion,omitempty"`
+}
@@ -0,0 +1,21 @@
+package model
+
+type Resources struct {
+	// required: false
+	Selector string `json:"selector,omitempty"`
+	// required: false
+	Containers []string `json:"containers,omitempty"`
+	// required: false
+	Cpu ResourceThreshold `json:"cpu,omitempty"`
+	// required: false
+	Memory ResourceThreshold `json:"memory,omitempty"`
+}
+
+type ResourceThreshold struct {
+	// required: false
+	Max string `json:"max,omitempty"`
+	// required: false
+	MaxPercent float64 `json:"maxPercent,omitempty"`
+	// required: false
+	Of string `json:"of,omitempty"`
+}
@@ -0,0 +1,36 @@
+package model
+
+type Scenario struct {
+	// required: false
+	Variables map[string]string `json:"variables,omitempty"`
+	// required: true
+	Steps []ScenarioStep `json:"steps,omitempty"`
+}
+
+type ScenarioStep struct {
+	// required: true
+	Name string `json:"name,omitempty"`
+	// required: false
+	Type string `json:"type,omitempty"`
+	// required: true
+	Url string `json:"url,omitempty"`
+	// required: false
+	Request Request `json:"request,omitempty"`
+	// required: false
+	Assertions []Assertion `json:"assertions,omitempty"`
+	// required: false
+	Extract []Extract `json:"extract,omitempty"`
+}
+
+type Extract struct {
+	// required: true
+	Variable string `json:"variable,omitempty"`
+	// required: true
+	Type string `json:"type,omitempty"`
+	// required: false
+	Name string `json:"name,omitempty"`
+	// required: false
+	Path string `json:"path,omitempty"`
+	// required: false
+	Pattern string `json:"pattern,omitempty"`
+}
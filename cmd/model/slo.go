@@ -0,0 +1,24 @@
+package model
+
+type Slo struct {
+	// required: true
+	Target float64 `json:"target,omitempty"`
+	// required: true
+	Window string `json:"window,omitempty"`
+}
+
+//swagger:model
+type SloReport struct {
+	// required: true
+	Id string `json:"id,omitempty"`
+	// required: true
+	Target float64 `json:"target,omitempty"`
+	// required: true
+	Window string `json:"window,omitempty"`
+	// required: true
+	Availability map[string]float64 `json:"availability,omitempty"`
+	// required: true
+	ErrorBudgetRemaining *float64 `json:"error_budget_remaining,omitempty"`
+	// required: true
+	BurnRate map[string]float64 `json:"burn_rate,omitempty"`
+}
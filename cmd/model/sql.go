@@ -0,0 +1,24 @@
+package model
+
+type Sql struct {
+	// required: true
+	Driver string `json:"driver,omitempty"`
+	// required: false
+	ConnectionString string `json:"connectionString,omitempty"`
+	// required: false
+	ConnectionStringEnv string `json:"connectionStringEnv,omitempty"`
+	// required: false
+	ConnectionStringFile string `json:"connectionStringFile,omitempty"`
+	// required: true
+	Query string `json:"query,omitempty"`
+	// required: false
+	MinRows *int `json:"minRows,omitempty"`
+	// required: false
+	MaxRows *int `json:"maxRows,omitempty"`
+	// required: false
+	Min *float64 `json:"min,omitempty"`
+	// required: false
+	Max *float64 `json:"max,omitempty"`
+	// required: false
+	Equals string `json:"equals,omitempty"`
+}
@@ -0,0 +1,16 @@
+package model
+
+type Sse struct {
+	// required: false
+	Events []string `json:"events,omitempty"`
+	// required: false
+	LabelPath string `json:"labelPath,omitempty"`
+	// required: false
+	Assertions []Assertion `json:"assertions,omitempty"`
+	// required: false
+	Mode string `json:"mode,omitempty"`
+	// required: false
+	MinMessages int `json:"minMessages,omitempty"`
+	// required: false
+	Interval int `json:"interval,omitempty"`
+}
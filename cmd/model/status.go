@@ -16,9 +16,19 @@ type Task struct {
 	// required: true
 	Online bool `json:"online,omitempty"`
 	// required: true
+	State string `json:"state,omitempty"`
+	// required: true
 	SuccessChecks int `json:"success_checks,omitempty"`
 	// required: true
 	FailureChecks int `json:"failure_checks,omitempty"`
 	// required: true
 	RestartTime int64 `json:"restartTime,omitempty"`
+	// required: true
+	LastResults []CheckResult `json:"last_results,omitempty"`
+	// required: true
+	LastFailureReason string `json:"last_failure_reason,omitempty"`
+	// required: true
+	LastStateChange int64 `json:"last_state_change,omitempty"`
+	// required: true
+	IncidentStart int64 `json:"incident_start,omitempty"`
 }
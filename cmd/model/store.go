@@ -0,0 +1,8 @@
+package model
+
+type Store struct {
+	// required: true
+	Type string `json:"type,omitempty"`
+	// required: true
+	Path string `json:"path,omitempty"`
+}
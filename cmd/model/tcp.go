@@ -0,0 +1,18 @@
+package model
+
+type Tcp struct {
+	// required: false
+	Send string `json:"send,omitempty"`
+	// required: false
+	Expect string `json:"expect,omitempty"`
+	// required: false
+	ExpectRegex string `json:"expectRegex,omitempty"`
+	// required: false
+	Tls string `json:"tls,omitempty"`
+	// required: false
+	StartTls []string `json:"startTls,omitempty"`
+	// required: false
+	ServerName string `json:"serverName,omitempty"`
+	// required: false
+	InsecureSkipVerify bool `json:"insecureSkipVerify,omitempty"`
+}
@@ -0,0 +1,14 @@
+package model
+
+type Tls struct {
+	// required: false
+	Enabled bool `json:"enabled,omitempty"`
+	// required: false
+	MinDaysValid float64 `json:"minDaysValid,omitempty"`
+	// required: false
+	MinVersion string `json:"minVersion,omitempty"`
+	// required: false
+	ServerName string `json:"serverName,omitempty"`
+	// required: false
+	CaFile string `json:"caFile,omitempty"`
+}
@@ -9,4 +9,6 @@ type WatchDogAction struct {
 	FailureThreshold int `json:"failureThreshold,omitempty"`
 	// required: true
 	AwaitAfterRestart int64 `json:"awaitAfterRestart,omitempty"`
+	// required: true
+	OnDegraded bool `json:"onDegraded,omitempty"`
 }
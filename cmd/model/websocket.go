@@ -0,0 +1,62 @@
+package model
+
+type Websocket struct {
+	// required: false
+	Protocol string `json:"protocol,omitempty"`
+	// required: false
+	Stomp Stomp `json:"stomp,omitempty"`
+	// required: false
+	SocketIo SocketIo `json:"socketIo,omitempty"`
+	// required: false
+	Auth string `json:"auth,omitempty"`
+	// required: false
+	AuthName string `json:"authName,omitempty"`
+	// required: false
+	AuthTemplate string `json:"authTemplate,omitempty"`
+	// required: false
+	Subprotocols []string `json:"subprotocols,omitempty"`
+	// required: false
+	Headers map[string]string `json:"headers,omitempty"`
+	// required: false
+	Subscribe string `json:"subscribe,omitempty"`
+	// required: false
+	LabelPath string `json:"labelPath,omitempty"`
+	// required: false
+	Assertions []Assertion `json:"assertions,omitempty"`
+	// required: false
+	Mode string `json:"mode,omitempty"`
+	// required: false
+	Message string `json:"message,omitempty"`
+	// required: false
+	ReplyTimeout int `json:"replyTimeout,omitempty"`
+	// required: false
+	MinMessages int `json:"minMessages,omitempty"`
+	// required: false
+	Interval int `json:"interval,omitempty"`
+}
+
+type Stomp struct {
+	// required: true
+	Destination string `json:"destination,omitempty"`
+	// required: false
+	SendDestination string `json:"sendDestination,omitempty"`
+	// required: false
+	Host string `json:"host,omitempty"`
+	// required: false
+	Login string `json:"login,omitempty"`
+	// required: false
+	Passcode string `json:"passcode,omitempty"`
+	// required: false
+	Headers map[string]string `json:"headers,omitempty"`
+}
+
+type SocketIo struct {
+	// required: false
+	Namespace string `json:"namespace,omitempty"`
+	// required: false
+	Events []string `json:"events,omitempty"`
+	// required: false
+	SubscribeEvent string `json:"subscribeEvent,omitempty"`
+	// required: false
+	RequestEvent string `json:"requestEvent,omitempty"`
+}
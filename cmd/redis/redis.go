@@ -1,19 +1,29 @@
 package redis
 
 import (
+	"bufio"
 	"context"
+	"crypto/tls"
+	"errors"
 	"fmt"
+	"strings"
+	"sync"
 
 	rediscli "github.com/go-redis/redis/v8"
 	"github.com/healthcheck-watchdog/cmd/common"
+	"github.com/healthcheck-watchdog/cmd/model"
 	log "github.com/sirupsen/logrus"
 )
 
 type Redis struct {
+	mx      sync.Mutex
+	clients map[string]rediscli.UniversalClient
 }
 
 func NewRedis() *Redis {
-	return &Redis{}
+	return &Redis{
+		clients: make(map[string]rediscli.UniversalClient),
+	}
 }
 
 func (redis *Redis) connect(cs string) *rediscli.Client {
@@ -42,3 +52,121 @@ func (redis *Redis) Execute(cs string, cmd string) error {
 
 	return nil
 }
+
+// Client returns cached client of the job. Addresses are sentinel addresses
+// in sentinel mode and seed nodes in cluster mode. Mode defaults to sentinel
+// with sentinel master and to standalone otherwise
+func (redis *Redis) Client(id string, addrs []string, config *model.Redis) (rediscli.UniversalClient, error) {
+	redis.mx.Lock()
+	defer redis.mx.Unlock()
+
+	if client, found := redis.clients[id]; found {
+		return client, nil
+	}
+	if err := Validate(addrs, config); err != nil {
+		return nil, err
+	}
+
+	options := &rediscli.UniversalOptions{
+		Addrs:            addrs,
+		Password:         config.Password,
+		DB:               config.Db,
+		MasterName:       config.SentinelMaster,
+		SentinelPassword: config.SentinelPassword,
+	}
+	if config.Tls {
+		options.TLSConfig = &tls.Config{
+			InsecureSkipVerify: config.InsecureSkipVerify, //nolint:gosec // opt-in by configuration
+		}
+	}
+
+	var client rediscli.UniversalClient
+	switch Mode(config) {
+	case common.RedisModeSentinel:
+		client = rediscli.NewFailoverClient(options.Failover())
+	case common.RedisModeCluster:
+		client = rediscli.NewClusterClient(options.Cluster())
+	default:
+		client = rediscli.NewClient(options.Simple())
+	}
+	redis.clients[id] = client
+
+	return client, nil
+}
+
+// Validate checks that addresses and sentinel master match the mode
+func Validate(addrs []string, config *model.Redis) error {
+	if len(addrs) == 0 {
+		return errors.New("missing redis address")
+	}
+
+	switch Mode(config) {
+	case common.RedisModeStandalone:
+		if len(addrs) > 1 {
+			return fmt.Errorf("standalone redis requires single address, got %d, set mode to cluster or sentinel", len(addrs))
+		}
+	case common.RedisModeSentinel:
+		if config.SentinelMaster == "" {
+			return errors.New("sentinel redis requires sentinelMaster")
+		}
+	case common.RedisModeCluster:
+	default:
+		return fmt.Errorf("unsupported redis mode %s", config.Mode)
+	}
+
+	for _, queue := range config.Queues {
+		switch queue.Type {
+		case "", common.RedisQueueList, common.RedisQueueStream:
+		default:
+			return fmt.Errorf("unsupported queue type %s of redis queue %s", queue.Type, queue.Key)
+		}
+	}
+
+	return nil
+}
+
+// Mode returns configured mode or its default
+func Mode(config *model.Redis) string {
+	switch {
+	case config.Mode != "":
+		return config.Mode
+	case config.SentinelMaster != "":
+		return common.RedisModeSentinel
+	}
+
+	return common.RedisModeStandalone
+}
+
+// Info returns INFO fields of default sections
+func Info(ctx context.Context, client rediscli.UniversalClient) (map[string]string, error) {
+	info, err := client.Info(ctx).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]string)
+	scanner := bufio.NewScanner(strings.NewReader(info))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if key, value, found := strings.Cut(line, ":"); found {
+			result[key] = value
+		}
+	}
+
+	return result, scanner.Err()
+}
+
+// Len returns length of list or stream queue
+func Len(ctx context.Context, client rediscli.UniversalClient, key string, queueType string) (int64, error) {
+	switch queueType {
+	case "", common.RedisQueueList:
+		return client.LLen(ctx, key).Result()
+	case common.RedisQueueStream:
+		return client.XLen(ctx, key).Result()
+	}
+
+	return 0, fmt.Errorf("unsupported queue type %s", queueType)
+}
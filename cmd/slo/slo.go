@@ -0,0 +1,206 @@
+package slo
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/healthcheck-watchdog/cmd/common"
+	"github.com/healthcheck-watchdog/cmd/model"
+	"github.com/healthcheck-watchdog/cmd/store"
+	log "github.com/sirupsen/logrus"
+)
+
+// Tracker calculates availability of jobs over rolling windows.
+// Check results are aggregated into fixed size time buckets.
+type Tracker struct {
+	mx     sync.Mutex
+	store  store.Store
+	series map[string]*series
+}
+
+type series struct {
+	// bucket count covering the longest window
+	size    int64
+	Buckets []Bucket `json:"buckets"`
+}
+
+type Bucket struct {
+	Start    int64 `json:"start"`
+	Total    int64 `json:"total"`
+	Failures int64 `json:"failures"`
+}
+
+func NewTracker(config *model.Config, st store.Store) *Tracker {
+	t := Tracker{
+		store:  st,
+		series: make(map[string]*series, len(config.Jobs)),
+	}
+
+	for i := range config.Jobs {
+		job := &config.Jobs[i]
+
+		longest, err := ParseWindow(common.SloWindows[len(common.SloWindows)-1])
+		if err != nil {
+			panic(err)
+		}
+		if job.Slo.Window != "" {
+			window, err := ParseWindow(job.Slo.Window)
+			if err != nil {
+				log.Error(fmt.Sprintf("%s: Invalid slo window %s: %s", job.Id, job.Slo.Window, err.Error()))
+			} else if window > longest {
+				longest = window
+			}
+		}
+
+		s := &series{
+			size: int64(longest.Seconds()) / common.SloBucketSize,
+		}
+		_, err = st.Get(common.StoreBucketSlo, job.Id, s)
+		if err != nil {
+			log.Error(fmt.Sprintf("%s: Failed to restore slo state: %s", job.Id, err.Error()))
+		}
+		t.series[job.Id] = s
+	}
+
+	return &t
+}
+
+// ParseWindow parses duration with additional day suffix, e.g. 30d
+func ParseWindow(window string) (time.Duration, error) {
+	if days, found := strings.CutSuffix(window, "d"); found {
+		value, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, err
+		}
+
+		return time.Duration(value) * 24 * time.Hour, nil
+	}
+
+	return time.ParseDuration(window)
+}
+
+// Add accounts check result in job series
+func (t *Tracker) Add(id string, result model.CheckResult) {
+	t.mx.Lock()
+	defer t.mx.Unlock()
+
+	s, found := t.series[id]
+	if !found || result.Status == common.StatusUnknown {
+		return
+	}
+
+	start := result.Timestamp - result.Timestamp%common.SloBucketSize
+	if len(s.Buckets) == 0 || s.Buckets[len(s.Buckets)-1].Start != start {
+		s.Buckets = append(s.Buckets, Bucket{Start: start})
+
+		// drop buckets out of the longest window
+		oldest := start - s.size*common.SloBucketSize
+		i := 0
+		for i < len(s.Buckets) && s.Buckets[i].Start <= oldest {
+			i++
+		}
+		s.Buckets = s.Buckets[i:]
+	}
+
+	// only down results spend error budget, degraded results count as available
+	bucket := &s.Buckets[len(s.Buckets)-1]
+	bucket.Total++
+	if result.Status == common.StatusDown {
+		bucket.Failures++
+	}
+
+	// persist current bucket on each check, so it is not lost on crash
+	t.save(id, s)
+}
+
+// Close persists current buckets of all jobs on shutdown
+func (t *Tracker) Close() {
+	t.mx.Lock()
+	defer t.mx.Unlock()
+
+	for id, s := range t.series {
+		if len(s.Buckets) > 0 {
+			t.save(id, s)
+		}
+	}
+}
+
+func (t *Tracker) save(id string, s *series) {
+	err := t.store.Put(common.StoreBucketSlo, id, s)
+	if err != nil {
+		log.Error(fmt.Sprintf("%s: Failed to save slo state: %s", id, err.Error()))
+	}
+}
+
+// counts returns total and failed checks within window ending now
+func (s *series) counts(now int64, window time.Duration) (total int64, failures int64) {
+	oldest := now - int64(window.Seconds())
+	for i := len(s.Buckets) - 1; i >= 0 && s.Buckets[i].Start > oldest; i-- {
+		total += s.Buckets[i].Total
+		failures += s.Buckets[i].Failures
+	}
+
+	return total, failures
+}
+
+// Report calculates availability, remaining error budget and burn rate of the job
+func (t *Tracker) Report(job *model.Job) *model.SloReport {
+	t.mx.Lock()
+	defer t.mx.Unlock()
+
+	report := &model.SloReport{
+		Id:           job.Id,
+		Target:       job.Slo.Target,
+		Availability: make(map[string]float64),
+	}
+
+	s, found := t.series[job.Id]
+	if !found {
+		return report
+	}
+
+	now := time.Now().Unix()
+	for _, w := range common.SloWindows {
+		window, _ := ParseWindow(w)
+		total, failures := s.counts(now, window)
+		if total > 0 {
+			report.Availability[w] = 100 * float64(total-failures) / float64(total)
+		}
+	}
+
+	if job.Slo.Target <= 0 || job.Slo.Target >= 100 {
+		return report
+	}
+
+	report.Window = job.Slo.Window
+	if report.Window == "" {
+		report.Window = common.DefaultSloWindow
+	}
+	window, err := ParseWindow(report.Window)
+	if err != nil {
+		return report
+	}
+
+	// allowed failure ratio
+	budget := 1 - job.Slo.Target/100
+
+	total, failures := s.counts(now, window)
+	if total > 0 {
+		remaining := 1 - (float64(failures)/float64(total))/budget
+		report.ErrorBudgetRemaining = &remaining
+	}
+
+	report.BurnRate = make(map[string]float64)
+	for _, w := range common.SloWindows {
+		window, _ := ParseWindow(w)
+		total, failures := s.counts(now, window)
+		if total > 0 {
+			report.BurnRate[w] = (float64(failures) / float64(total)) / budget
+		}
+	}
+
+	return report
+}
@@ -0,0 +1,173 @@
+package slo
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/healthcheck-watchdog/cmd/common"
+	"github.com/healthcheck-watchdog/cmd/model"
+	"github.com/healthcheck-watchdog/cmd/store"
+)
+
+func result(timestamp int64, status string) model.CheckResult {
+	return model.CheckResult{Timestamp: timestamp, Status: status}
+}
+
+func TestParseWindow(t *testing.T) {
+	tests := map[string]time.Duration{
+		"30d": 30 * 24 * time.Hour,
+		"1h":  time.Hour,
+		"90m": 90 * time.Minute,
+	}
+	for window, expected := range tests {
+		if value, err := ParseWindow(window); err != nil || value != expected {
+			t.Errorf("%s: expected %s, got %s %v", window, expected, value, err)
+		}
+	}
+	for _, window := range []string{"xd", "1w", ""} {
+		if _, err := ParseWindow(window); err == nil {
+			t.Errorf("%s: expected error", window)
+		}
+	}
+}
+
+func TestRollover(t *testing.T) {
+	st := store.NewMemoryStore()
+	tracker := NewTracker(&model.Config{Jobs: []model.Job{{Id: "api"}}}, st)
+
+	start := int64(1_700_000_100) - 1_700_000_100%common.SloBucketSize
+	tracker.Add("api", result(start, common.StatusUp))
+	tracker.Add("api", result(start+10, common.StatusDown))
+	tracker.Add("api", result(start+20, common.StatusDegraded))
+
+	// current bucket is saved on each check, degraded result is not a failure
+	saved := &series{}
+	if found, _ := st.Get(common.StoreBucketSlo, "api", saved); !found ||
+		len(saved.Buckets) != 1 || saved.Buckets[0] != (Bucket{Start: start, Total: 3, Failures: 1}) {
+		t.Fatalf("expected current bucket saved, got %+v", saved.Buckets)
+	}
+
+	tracker.Add("api", result(start+common.SloBucketSize, common.StatusUp))
+	if found, _ := st.Get(common.StoreBucketSlo, "api", saved); !found || len(saved.Buckets) != 2 ||
+		saved.Buckets[1] != (Bucket{Start: start + common.SloBucketSize, Total: 1}) {
+		t.Fatalf("expected new bucket saved, got %+v", saved.Buckets)
+	}
+
+	buckets := tracker.series["api"].Buckets
+	if len(buckets) != 2 || buckets[1] != (Bucket{Start: start + common.SloBucketSize, Total: 1}) {
+		t.Errorf("expected new bucket, got %+v", buckets)
+	}
+
+	// buckets out of the longest window are dropped
+	longest, _ := ParseWindow(common.SloWindows[len(common.SloWindows)-1])
+	tracker.Add("api", result(start+int64(longest.Seconds())+common.SloBucketSize, common.StatusUp))
+	buckets = tracker.series["api"].Buckets
+	if len(buckets) != 1 || buckets[0].Start != start+int64(longest.Seconds())+common.SloBucketSize {
+		t.Errorf("expected old buckets dropped, got %+v", buckets)
+	}
+}
+
+func TestIgnoredResults(t *testing.T) {
+	tracker := NewTracker(&model.Config{Jobs: []model.Job{{Id: "api"}}}, store.NewMemoryStore())
+	tracker.Add("api", result(time.Now().Unix(), common.StatusUnknown))
+	tracker.Add("other", result(time.Now().Unix(), common.StatusDown))
+
+	if len(tracker.series["api"].Buckets) != 0 || tracker.series["other"] != nil {
+		t.Errorf("expected unknown results and unknown jobs ignored, got %+v", tracker.series)
+	}
+}
+
+func TestReport(t *testing.T) {
+	job := model.Job{Id: "api", Slo: model.Slo{Target: 99, Window: "24h"}}
+	tracker := NewTracker(&model.Config{Jobs: []model.Job{job}}, store.NewMemoryStore())
+
+	now := time.Now().Unix()
+	// 2 of 10 checks failed 2 hours ago, 1 of 10 checks failed within the last hour
+	for i := int64(0); i < 10; i++ {
+		status := common.StatusUp
+		if i < 2 {
+			status = common.StatusDown
+		}
+		tracker.Add("api", result(now-2*3600, status))
+	}
+	for i := int64(0); i < 10; i++ {
+		status := common.StatusUp
+		if i < 1 {
+			status = common.StatusDown
+		}
+		tracker.Add("api", result(now-60, status))
+	}
+
+	report := tracker.Report(&job)
+	if report.Window != "24h" || report.Target != 99 {
+		t.Errorf("unexpected report target %v window %s", report.Target, report.Window)
+	}
+
+	availability := map[string]float64{"1h": 90, "24h": 85, "7d": 85, "30d": 85}
+	for w, expected := range availability {
+		if math.Abs(report.Availability[w]-expected) > 1e-9 {
+			t.Errorf("%s: expected availability %v, got %v", w, expected, report.Availability[w])
+		}
+	}
+
+	// 3 of 20 checks failed with 1% budget: budget is overspent 15 times
+	if report.ErrorBudgetRemaining == nil || math.Abs(*report.ErrorBudgetRemaining-(1-15)) > 1e-9 {
+		t.Errorf("expected error budget remaining -14, got %v", report.ErrorBudgetRemaining)
+	}
+
+	burnRate := map[string]float64{"1h": 10, "24h": 15, "7d": 15, "30d": 15}
+	for w, expected := range burnRate {
+		if math.Abs(report.BurnRate[w]-expected) > 1e-9 {
+			t.Errorf("%s: expected burn rate %v, got %v", w, expected, report.BurnRate[w])
+		}
+	}
+}
+
+func TestReportWithoutTarget(t *testing.T) {
+	job := model.Job{Id: "api"}
+	tracker := NewTracker(&model.Config{Jobs: []model.Job{job}}, store.NewMemoryStore())
+	tracker.Add("api", result(time.Now().Unix(), common.StatusUp))
+
+	report := tracker.Report(&job)
+	if report.Availability["1h"] != 100 || report.ErrorBudgetRemaining != nil || report.BurnRate != nil {
+		t.Errorf("expected availability only, got %+v", report)
+	}
+}
+
+func TestCloseSavesCurrentBucket(t *testing.T) {
+	st := store.NewMemoryStore()
+	config := &model.Config{Jobs: []model.Job{{Id: "api"}, {Id: "idle"}}}
+	tracker := NewTracker(config, st)
+
+	now := time.Now().Unix()
+	tracker.Add("api", result(now, common.StatusDown))
+	tracker.Close()
+
+	if found, _ := st.Get(common.StoreBucketSlo, "idle", &series{}); found {
+		t.Error("expected job without checks not saved")
+	}
+
+	restored := NewTracker(config, st)
+	buckets := restored.series["api"].Buckets
+	if len(buckets) != 1 || buckets[0].Total != 1 || buckets[0].Failures != 1 {
+		t.Errorf("expected current bucket restored, got %+v", buckets)
+	}
+}
+
+func TestRestoreWithoutClose(t *testing.T) {
+	st := store.NewMemoryStore()
+	config := &model.Config{Jobs: []model.Job{{Id: "api"}}}
+	tracker := NewTracker(config, st)
+
+	now := time.Now().Unix()
+	tracker.Add("api", result(now, common.StatusUp))
+	tracker.Add("api", result(now, common.StatusDown))
+
+	// checks are kept after crash without close
+	restored := NewTracker(config, st)
+	buckets := restored.series["api"].Buckets
+	if len(buckets) != 1 || buckets[0].Total != 2 || buckets[0].Failures != 1 {
+		t.Errorf("expected current bucket restored, got %+v", buckets)
+	}
+}
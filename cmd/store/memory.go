@@ -0,0 +1,60 @@
+package store
+
+import (
+	"encoding/json"
+	"sync"
+)
+
+// MemoryStore is a Store without persistence. Used when file database is not available
+type MemoryStore struct {
+	mx      sync.Mutex
+	buckets map[string]map[string][]byte
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		buckets: make(map[string]map[string][]byte),
+	}
+}
+
+func (s *MemoryStore) Get(bucket string, key string, v interface{}) (bool, error) {
+	s.mx.Lock()
+	data, found := s.buckets[bucket][key]
+	s.mx.Unlock()
+
+	if !found {
+		return false, nil
+	}
+
+	return true, json.Unmarshal(data, v)
+}
+
+func (s *MemoryStore) Put(bucket string, key string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	if s.buckets[bucket] == nil {
+		s.buckets[bucket] = make(map[string][]byte)
+	}
+	s.buckets[bucket][key] = data
+
+	return nil
+}
+
+func (s *MemoryStore) Delete(bucket string, key string) error {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	delete(s.buckets[bucket], key)
+
+	return nil
+}
+
+func (s *MemoryStore) Close() error {
+	return nil
+}
@@ -0,0 +1,57 @@
+package store
+
+import (
+	"fmt"
+
+	"github.com/healthcheck-watchdog/cmd/common"
+	"github.com/healthcheck-watchdog/cmd/model"
+	log "github.com/sirupsen/logrus"
+)
+
+// Store keeps watchdog state between restarts. Values are stored as json
+// documents grouped into buckets.
+type Store interface {
+	// Get loads value by key into v. Returns false if key is missing
+	Get(bucket string, key string, v interface{}) (bool, error)
+	// Put saves value by key
+	Put(bucket string, key string, v interface{}) error
+	// Delete removes value by key
+	Delete(bucket string, key string) error
+	// Close releases underlying resources
+	Close() error
+}
+
+// NewStore opens configured backend. A configured store.path that can't be
+// opened fails startup, so the watchdog does not silently lose its state on
+// restart. Only the implicit default path falls back to memory with a warning,
+// e.g. running outside the image or without a volume mounted on /data
+func NewStore(config *model.Config) (Store, error) {
+	return newStore(config, common.DefaultStorePath)
+}
+
+func newStore(config *model.Config, defaultPath string) (Store, error) {
+	switch config.Store.Type {
+	case common.StoreTypeMemory:
+		log.Warn("State store initialized in memory. State will not survive restart")
+		return NewMemoryStore(), nil
+	case "", common.StoreTypeBolt:
+		path := config.Store.Path
+		if path == "" {
+			path = defaultPath
+		}
+
+		s, err := NewBoltStore(path)
+		if err != nil && config.Store.Path == "" {
+			log.Warn(fmt.Sprintf("Failed to open default state store %s: %s. State will not survive restart", path, err.Error()))
+			return NewMemoryStore(), nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("open state store %s: %w", path, err)
+		}
+		log.Info(fmt.Sprintf("State store initialized on %s", path))
+
+		return s, nil
+	default:
+		return nil, fmt.Errorf("unknown state store type %s", config.Store.Type)
+	}
+}
@@ -0,0 +1,145 @@
+package store
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/healthcheck-watchdog/cmd/common"
+	"github.com/healthcheck-watchdog/cmd/model"
+)
+
+func TestRoundTrip(t *testing.T) {
+	bolt, err := NewBoltStore(filepath.Join(t.TempDir(), "state.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stores := map[string]Store{"bolt": bolt, "memory": NewMemoryStore()}
+	for name, s := range stores {
+		t.Run(name, func(t *testing.T) {
+			defer s.Close()
+
+			var value int32
+			if found, err := s.Get(common.StoreBucketReplicas, "api", &value); found || err != nil {
+				t.Errorf("expected missing bucket, got %t %v", found, err)
+			}
+
+			if err := s.Put(common.StoreBucketReplicas, "api", int32(3)); err != nil {
+				t.Fatal(err)
+			}
+			if found, err := s.Get(common.StoreBucketReplicas, "api", &value); !found || err != nil || value != 3 {
+				t.Errorf("expected 3, got %d %t %v", value, found, err)
+			}
+			if found, _ := s.Get(common.StoreBucketReplicas, "web", &value); found {
+				t.Error("expected missing key")
+			}
+			if found, _ := s.Get(common.StoreBucketTasks, "api", &value); found {
+				t.Error("expected keys of other buckets not found")
+			}
+
+			if err := s.Put(common.StoreBucketReplicas, "api", int32(5)); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := s.Get(common.StoreBucketReplicas, "api", &value); err != nil || value != 5 {
+				t.Errorf("expected overwritten value 5, got %d %v", value, err)
+			}
+
+			if err := s.Delete(common.StoreBucketReplicas, "api"); err != nil {
+				t.Fatal(err)
+			}
+			if found, _ := s.Get(common.StoreBucketReplicas, "api", &value); found {
+				t.Error("expected deleted key")
+			}
+			if err := s.Delete(common.StoreBucketSlo, "api"); err != nil {
+				t.Errorf("expected delete in missing bucket ignored, got %v", err)
+			}
+
+			var task model.Task
+			if err := s.Put(common.StoreBucketTasks, "api", "not a task"); err != nil {
+				t.Fatal(err)
+			}
+			if found, err := s.Get(common.StoreBucketTasks, "api", &task); !found || err == nil {
+				t.Errorf("expected decode error, got %t %v", found, err)
+			}
+		})
+	}
+}
+
+func TestReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.db")
+	s, err := NewBoltStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	task := model.Task{Id: "api", FailureChecks: 2, RestartTime: 1700000000, IncidentStart: 1699999000}
+	if err := s.Put(common.StoreBucketTasks, task.Id, &task); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Put(common.StoreBucketReplicas, "prod/api", int32(3)); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err = NewBoltStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	restored := model.Task{}
+	if found, err := s.Get(common.StoreBucketTasks, "api", &restored); !found || err != nil || !reflect.DeepEqual(restored, task) {
+		t.Errorf("expected task %+v restored, got %+v %t %v", task, restored, found, err)
+	}
+	var replicas int32
+	if found, err := s.Get(common.StoreBucketReplicas, "prod/api", &replicas); !found || err != nil || replicas != 3 {
+		t.Errorf("expected 3 replicas restored, got %d %t %v", replicas, found, err)
+	}
+}
+
+func TestNewStore(t *testing.T) {
+	dir := t.TempDir()
+	missing := filepath.Join(dir, "missing", "state.db")
+
+	tests := []struct {
+		name        string
+		store       model.Store
+		defaultPath string
+		expected    string
+		failure     string
+	}{
+		{name: "memory", store: model.Store{Type: "memory"}, defaultPath: missing, expected: "memory"},
+		{name: "default", defaultPath: filepath.Join(dir, "default.db"), expected: "bolt"},
+		{name: "default fallback", defaultPath: missing, expected: "memory"},
+		{name: "configured", store: model.Store{Type: "bolt", Path: filepath.Join(dir, "configured.db")}, defaultPath: missing, expected: "bolt"},
+		{name: "configured unavailable", store: model.Store{Path: missing}, defaultPath: filepath.Join(dir, "default.db"),
+			failure: "open state store " + missing},
+		{name: "type", store: model.Store{Type: "redis"}, failure: "unknown state store type redis"},
+	}
+	for _, tt := range tests {
+		s, err := newStore(&model.Config{Store: tt.store}, tt.defaultPath)
+		if tt.failure != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.failure) {
+				t.Errorf("%s: expected %q, got %v", tt.name, tt.failure, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.name, err)
+			continue
+		}
+
+		kind := "memory"
+		if _, ok := s.(*BoltStore); ok {
+			kind = "bolt"
+		}
+		if kind != tt.expected {
+			t.Errorf("%s: expected %s store, got %s", tt.name, tt.expected, kind)
+		}
+		s.Close()
+	}
+}
@@ -2,10 +2,14 @@ package watchdog
 
 import (
 	"fmt"
+	"sync"
+	"time"
 
 	"github.com/healthcheck-watchdog/cmd/cluster"
+	"github.com/healthcheck-watchdog/cmd/common"
 	"github.com/healthcheck-watchdog/cmd/model"
 	"github.com/healthcheck-watchdog/cmd/redis"
+	"github.com/healthcheck-watchdog/cmd/store"
 	log "github.com/sirupsen/logrus"
 )
 
@@ -13,9 +17,13 @@ type WatchDog struct {
 	cluster *cluster.Cluster
 	redis   *redis.Redis
 	config  *model.Config
+	store   store.Store
+	// unix time of the last successful execution by action id
+	mx          sync.Mutex
+	lastActions map[string]int64
 }
 
-func NewWatchDog(cl *cluster.Cluster, config *model.Config) *WatchDog {
+func NewWatchDog(cl *cluster.Cluster, config *model.Config, st store.Store) *WatchDog {
 	if config.WatchDog.Namespace == "" && 
 		len(config.WatchDog.Actions) == 0 {
 			log.Info("Missing watchdog configuration. Watchdog configuration ignored.")
@@ -26,6 +34,21 @@ func NewWatchDog(cl *cluster.Cluster, config *model.Config) *WatchDog {
 		cluster: cl,
 		redis:   redis.NewRedis(),
 		config:  config,
+		store:   st,
+
+		lastActions: make(map[string]int64),
+	}
+
+	// restore action times saved before restart
+	for _, action := range config.WatchDog.Actions {
+		var value int64
+		found, err := st.Get(common.StoreBucketActions, action.Id, &value)
+		if err != nil {
+			log.Error(fmt.Sprintf("Failed to restore action %s time: %s", action.Id, err.Error()))
+		} else if found {
+			wd.lastActions[action.Id] = value
+			log.Info(fmt.Sprintf("Restored action %s time: %s", action.Id, time.Unix(value, 0).UTC().Format(time.RFC3339)))
+		}
 	}
 
 	return &wd
@@ -46,6 +69,9 @@ func (ws *WatchDog) Execute(tasks []string) {
 				case "deployment_scale_up":
 					err = ws.cluster.ScaleUp(ws.config.WatchDog.Actions[y].Items, ws.config.WatchDog.Namespace)
 				}
+				if err == nil {
+					ws.saveActionTime(ws.config.WatchDog.Actions[y].Id)
+				}
 			}
 
 			if err != nil {
@@ -54,3 +80,34 @@ func (ws *WatchDog) Execute(tasks []string) {
 		}
 	}
 }
+
+// saveActionTime remembers when action was executed last time
+func (ws *WatchDog) saveActionTime(id string) {
+	now := time.Now().Unix()
+	ws.mx.Lock()
+	ws.lastActions[id] = now
+	ws.mx.Unlock()
+
+	err := ws.store.Put(common.StoreBucketActions, id, now)
+	if err != nil {
+		log.Error(fmt.Sprintf("Failed to save action %s time: %s", id, err.Error()))
+	}
+}
+
+// LastActionTime returns unix time of the latest successful execution of the
+// actions, 0 if none was executed
+func (ws *WatchDog) LastActionTime(ids []string) int64 {
+	if ws == nil {
+		return 0
+	}
+
+	ws.mx.Lock()
+	defer ws.mx.Unlock()
+
+	var last int64
+	for _, id := range ids {
+		last = max(last, ws.lastActions[id])
+	}
+
+	return last
+}
@@ -0,0 +1,43 @@
+package watchdog
+
+import (
+	"testing"
+	"time"
+
+	"github.com/healthcheck-watchdog/cmd/common"
+	"github.com/healthcheck-watchdog/cmd/model"
+	"github.com/healthcheck-watchdog/cmd/store"
+)
+
+func TestActionTimeRestored(t *testing.T) {
+	config := &model.Config{WatchDog: model.WatchDog{Actions: []model.Action{{Id: "restart"}, {Id: "flush"}, {Id: "scale"}}}}
+	st := store.NewMemoryStore()
+	if err := st.Put(common.StoreBucketActions, "scale", int64(100)); err != nil {
+		t.Fatal(err)
+	}
+
+	wd := NewWatchDog(nil, config, st)
+	if last := wd.LastActionTime([]string{"restart", "scale"}); last != 100 {
+		t.Errorf("expected restored action time 100, got %d", last)
+	}
+
+	start := time.Now().Unix()
+	wd.Execute([]string{"restart", "unknown"})
+	if last := wd.LastActionTime([]string{"restart"}); last < start {
+		t.Errorf("expected action time saved, got %d", last)
+	}
+	if last := wd.LastActionTime([]string{"flush"}); last != 0 {
+		t.Errorf("expected action not executed, got %d", last)
+	}
+
+	// action times survive restart
+	wd = NewWatchDog(nil, config, st)
+	if last := wd.LastActionTime([]string{"scale", "restart"}); last < start {
+		t.Errorf("expected latest action time restored, got %d", last)
+	}
+
+	var nilWatchDog *WatchDog
+	if last := nilWatchDog.LastActionTime([]string{"restart"}); last != 0 {
+		t.Errorf("expected 0 without watchdog, got %d", last)
+	}
+}